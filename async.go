@@ -0,0 +1,155 @@
+package callonce
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+const defaultAsyncBufferSize = 1024
+
+// OverflowPolicy decides what an AsyncObserver does when its buffer is full.
+type OverflowPolicy int
+
+const (
+	// DropOnFull discards the event and increments the dropped counter.
+	// Get never blocks on the observer.
+	DropOnFull OverflowPolicy = iota
+	// BlockOnFull makes On wait until the background goroutine frees a slot.
+	BlockOnFull
+)
+
+// AsyncOption configures an AsyncObserver created by NewAsyncObserver.
+type AsyncOption func(*AsyncObserver)
+
+// WithAsyncBuffer sets the capacity of the ring buffer. Values below 1 are
+// ignored and the default of 1024 is used.
+func WithAsyncBuffer(size int) AsyncOption {
+	return func(a *AsyncObserver) {
+		if size > 0 {
+			a.buf = make([]EventData, size)
+		}
+	}
+}
+
+// WithOverflowPolicy sets what happens when the buffer is full. The default
+// is DropOnFull.
+func WithOverflowPolicy(p OverflowPolicy) AsyncOption {
+	return func(a *AsyncObserver) {
+		a.policy = p
+	}
+}
+
+// AsyncObserver is an Observer that moves event delivery off the Get hot
+// path. Events are enqueued into a bounded ring buffer and handed to the
+// wrapped Observer, in order, by a single background goroutine.
+//
+// An AsyncObserver can be shared by many caches. Call Close when it is no
+// longer needed to deliver outstanding events and stop the goroutine.
+type AsyncObserver struct {
+	next   Observer
+	policy OverflowPolicy
+
+	mu       sync.Mutex
+	ready    sync.Cond // signalled when an event is enqueued or on Close
+	drained  sync.Cond // signalled when a slot frees up or delivery finishes
+	buf      []EventData
+	head     int
+	size     int
+	busy     bool
+	closed   bool
+	finished chan struct{}
+
+	dropped atomic.Uint64
+}
+
+// NewAsyncObserver returns an AsyncObserver that forwards events to next
+// from a background goroutine.
+func NewAsyncObserver(next Observer, opts ...AsyncOption) *AsyncObserver {
+	a := &AsyncObserver{
+		next:     next,
+		finished: make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(a)
+	}
+	if a.buf == nil {
+		a.buf = make([]EventData, defaultAsyncBufferSize)
+	}
+	a.ready.L = &a.mu
+	a.drained.L = &a.mu
+	go a.run()
+	return a
+}
+
+// On enqueues eventData for delivery. Events received after Close are
+// counted as dropped.
+func (a *AsyncObserver) On(eventData EventData) {
+	a.mu.Lock()
+	for a.size == len(a.buf) && a.policy == BlockOnFull && !a.closed {
+		a.drained.Wait()
+	}
+	if a.closed || a.size == len(a.buf) {
+		a.mu.Unlock()
+		a.dropped.Add(1)
+		return
+	}
+	a.buf[(a.head+a.size)%len(a.buf)] = eventData
+	a.size++
+	a.mu.Unlock()
+	a.ready.Signal()
+}
+
+// Dropped reports how many events were discarded because the buffer was
+// full or the observer was closed.
+func (a *AsyncObserver) Dropped() uint64 {
+	return a.dropped.Load()
+}
+
+// Flush blocks until every event enqueued before the call has been
+// delivered to the wrapped Observer.
+func (a *AsyncObserver) Flush() {
+	a.mu.Lock()
+	for a.size > 0 || a.busy {
+		a.drained.Wait()
+	}
+	a.mu.Unlock()
+}
+
+// Close delivers any buffered events, stops the background goroutine and
+// waits for it to exit. It is safe to call Close more than once.
+func (a *AsyncObserver) Close() {
+	a.mu.Lock()
+	a.closed = true
+	a.mu.Unlock()
+	a.ready.Broadcast()
+	a.drained.Broadcast()
+	<-a.finished
+}
+
+func (a *AsyncObserver) run() {
+	defer close(a.finished)
+	for {
+		a.mu.Lock()
+		for a.size == 0 && !a.closed {
+			a.ready.Wait()
+		}
+		if a.size == 0 {
+			a.mu.Unlock()
+			return
+		}
+		e := a.buf[a.head]
+		a.buf[a.head] = EventData{}
+		a.head = (a.head + 1) % len(a.buf)
+		a.size--
+		a.busy = true
+		a.mu.Unlock()
+		a.drained.Broadcast()
+
+		a.next.On(e)
+
+		a.mu.Lock()
+		a.busy = false
+		a.mu.Unlock()
+		a.drained.Broadcast()
+	}
+}
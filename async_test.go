@@ -0,0 +1,114 @@
+package callonce_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	callonce "github.com/probablyarth/callonce-go"
+)
+
+type recordingObserver struct {
+	mu     sync.Mutex
+	events []callonce.EventData
+}
+
+func (o *recordingObserver) On(e callonce.EventData) {
+	o.mu.Lock()
+	o.events = append(o.events, e)
+	o.mu.Unlock()
+}
+
+func (o *recordingObserver) snapshot() []callonce.EventData {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return append([]callonce.EventData(nil), o.events...)
+}
+
+// blockingObserver holds up delivery until release is closed.
+type blockingObserver struct {
+	recordingObserver
+	release chan struct{}
+}
+
+func (o *blockingObserver) On(e callonce.EventData) {
+	<-o.release
+	o.recordingObserver.On(e)
+}
+
+func TestAsyncObserverDeliversInOrder(t *testing.T) {
+	rec := &recordingObserver{}
+	async := callonce.NewAsyncObserver(rec)
+	defer async.Close()
+
+	ctx := callonce.WithCache(context.Background(), callonce.WithObserver(async))
+	key := callonce.NewKey[string]("async")
+
+	callonce.Get(ctx, func() (string, error) { return "v", nil }, callonce.L(key, "1"))
+	callonce.Get(ctx, func() (string, error) { return "v", nil }, callonce.L(key, "1"))
+	async.Flush()
+
+	events := rec.snapshot()
+	if len(events) != 2 {
+		t.Fatalf("got %d events, want 2", len(events))
+	}
+	if events[0].Event != callonce.EventMiss || events[1].Event != callonce.EventHit {
+		t.Fatalf("got events %v, %v; want miss, hit", events[0].Event, events[1].Event)
+	}
+	if async.Dropped() != 0 {
+		t.Fatalf("dropped = %d, want 0", async.Dropped())
+	}
+}
+
+func TestAsyncObserverDropsWhenFull(t *testing.T) {
+	obs := &blockingObserver{release: make(chan struct{})}
+	async := callonce.NewAsyncObserver(obs, callonce.WithAsyncBuffer(2))
+
+	// The first event is picked up by the worker and blocks there; the next
+	// two fill the buffer and the remaining ones are dropped.
+	for i := 0; i < 10; i++ {
+		async.On(callonce.EventData{Event: callonce.EventHit, Identifier: "x"})
+	}
+	close(obs.release)
+	async.Close()
+
+	delivered := uint64(len(obs.snapshot()))
+	if delivered+async.Dropped() != 10 {
+		t.Fatalf("delivered %d + dropped %d, want 10", delivered, async.Dropped())
+	}
+	if async.Dropped() < 7 {
+		t.Fatalf("dropped = %d, want at least 7", async.Dropped())
+	}
+}
+
+func TestAsyncObserverBlockPolicy(t *testing.T) {
+	rec := &recordingObserver{}
+	async := callonce.NewAsyncObserver(rec,
+		callonce.WithAsyncBuffer(1),
+		callonce.WithOverflowPolicy(callonce.BlockOnFull),
+	)
+
+	for i := 0; i < 100; i++ {
+		async.On(callonce.EventData{Event: callonce.EventMiss})
+	}
+	async.Close()
+
+	if n := len(rec.snapshot()); n != 100 {
+		t.Fatalf("delivered %d events, want 100", n)
+	}
+	if async.Dropped() != 0 {
+		t.Fatalf("dropped = %d, want 0", async.Dropped())
+	}
+}
+
+func TestAsyncObserverDropsAfterClose(t *testing.T) {
+	rec := &recordingObserver{}
+	async := callonce.NewAsyncObserver(rec)
+	async.Close()
+	async.Close()
+
+	async.On(callonce.EventData{Event: callonce.EventHit})
+	if async.Dropped() != 1 {
+		t.Fatalf("dropped = %d, want 1", async.Dropped())
+	}
+}
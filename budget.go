@@ -0,0 +1,101 @@
+package callonce
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrBudgetExceeded is matched by errors.Is for the *BudgetError that Get
+// returns when a miss budget is exhausted.
+var ErrBudgetExceeded = errors.New("callonce: miss budget exceeded")
+
+// BudgetError reports that Get did not call fn because a miss budget set
+// with WithMissBudget or WithKeyMissBudget was exhausted.
+type BudgetError struct {
+	// Key is the key name of the lookup, or empty if the cache-wide budget
+	// was exhausted.
+	Key string
+	// Limit is the exhausted budget.
+	Limit int
+}
+
+func (e *BudgetError) Error() string {
+	if e.Key == "" {
+		return fmt.Sprintf("callonce: miss budget of %d exceeded", e.Limit)
+	}
+	return fmt.Sprintf("callonce: miss budget of %d exceeded for key %s", e.Limit, e.Key)
+}
+
+// Unwrap returns ErrBudgetExceeded.
+func (e *BudgetError) Unwrap() error {
+	return ErrBudgetExceeded
+}
+
+// WithMissBudget caps the number of fn calls the cache makes. Once n
+// misses have called fn, further misses return a *BudgetError instead.
+// Hits and dedups are never limited. Failed calls count toward the budget.
+// Negative values are ignored.
+func WithMissBudget(n int) Option {
+	return func(cache *Cache) {
+		if n >= 0 {
+			cache.ensureBudget().limit = n
+		}
+	}
+}
+
+// WithKeyMissBudget caps the number of fn calls the cache makes for key,
+// in addition to any cache-wide budget. Negative values are ignored.
+func WithKeyMissBudget[T any](key Key[T], n int) Option {
+	return func(cache *Cache) {
+		if n < 0 {
+			return
+		}
+		b := cache.ensureBudget()
+		if b.keyLimits == nil {
+			b.keyLimits = make(map[string]int)
+			b.keyUsed = make(map[string]int)
+		}
+		b.keyLimits[key.name] = n
+	}
+}
+
+type missBudget struct {
+	limit     int // negative means unlimited
+	keyLimits map[string]int
+
+	mu      sync.Mutex
+	used    int
+	keyUsed map[string]int
+}
+
+func (c *Cache) ensureBudget() *missBudget {
+	if c.budget == nil {
+		c.budget = &missBudget{limit: -1}
+	}
+	return c.budget
+}
+
+// reserveMiss consumes one unit of the cache-wide budget and of keyName's
+// budget, or returns a *BudgetError without consuming anything.
+func (c *Cache) reserveMiss(keyName string) error {
+	b := c.budget
+	if b == nil {
+		return nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	keyLimit, hasKeyLimit := b.keyLimits[keyName]
+	if hasKeyLimit && b.keyUsed[keyName] >= keyLimit {
+		return &BudgetError{Key: keyName, Limit: keyLimit}
+	}
+	if b.limit >= 0 && b.used >= b.limit {
+		return &BudgetError{Limit: b.limit}
+	}
+	b.used++
+	if hasKeyLimit {
+		b.keyUsed[keyName]++
+	}
+	return nil
+}
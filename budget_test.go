@@ -0,0 +1,87 @@
+package callonce_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+
+	callonce "github.com/probablyarth/callonce-go"
+)
+
+func TestMissBudget(t *testing.T) {
+	ctx := callonce.WithCache(context.Background(), callonce.WithMissBudget(2))
+	var calls atomic.Int32
+	fn := func() (string, error) {
+		calls.Add(1)
+		return "v", nil
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := callonce.Get(ctx, fn, callonce.L(testKey, fmt.Sprint(i))); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	_, err := callonce.Get(ctx, fn, callonce.L(testKey, "2"))
+	if !errors.Is(err, callonce.ErrBudgetExceeded) {
+		t.Fatalf("err = %v, want ErrBudgetExceeded", err)
+	}
+	var be *callonce.BudgetError
+	if !errors.As(err, &be) || be.Limit != 2 || be.Key != "" {
+		t.Fatalf("got %#v, want cache-wide BudgetError with limit 2", be)
+	}
+	if n := calls.Load(); n != 2 {
+		t.Fatalf("fn called %d times, want 2", n)
+	}
+
+	// Hits are not limited.
+	if v, err := callonce.Get(ctx, fn, callonce.L(testKey, "0")); err != nil || v != "v" {
+		t.Fatalf("hit returned %q, %v", v, err)
+	}
+}
+
+func TestKeyMissBudget(t *testing.T) {
+	limited := callonce.NewKey[string]("limited")
+	ctx := callonce.WithCache(context.Background(), callonce.WithKeyMissBudget(limited, 1))
+	fn := func() (string, error) { return "v", nil }
+
+	if _, err := callonce.Get(ctx, fn, callonce.L(limited, "1")); err != nil {
+		t.Fatal(err)
+	}
+	_, err := callonce.Get(ctx, fn, callonce.L(limited, "2"))
+	var be *callonce.BudgetError
+	if !errors.As(err, &be) || be.Key != "string:limited" || be.Limit != 1 {
+		t.Fatalf("err = %v, want BudgetError for string:limited", err)
+	}
+
+	// Other keys are unaffected.
+	for i := 0; i < 5; i++ {
+		if _, err := callonce.Get(ctx, fn, callonce.L(testKey, fmt.Sprint(i))); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestMissBudgetCountsErrors(t *testing.T) {
+	ctx := callonce.WithCache(context.Background(), callonce.WithMissBudget(1))
+
+	callonce.Get(ctx, func() (string, error) { return "", errors.New("fail") }, callonce.L(testKey, "1"))
+	_, err := callonce.Get(ctx, func() (string, error) { return "ok", nil }, callonce.L(testKey, "1"))
+	if !errors.Is(err, callonce.ErrBudgetExceeded) {
+		t.Fatalf("err = %v, want ErrBudgetExceeded", err)
+	}
+}
+
+func TestMissBudgetIgnoresNegative(t *testing.T) {
+	ctx := callonce.WithCache(context.Background(),
+		callonce.WithMissBudget(-1),
+		callonce.WithKeyMissBudget(testKey, -1),
+	)
+	for i := 0; i < 3; i++ {
+		if _, err := callonce.Get(ctx, func() (string, error) { return "v", nil }, callonce.L(testKey, fmt.Sprint(i))); err != nil {
+			t.Fatalf("miss %d: %v", i, err)
+		}
+	}
+}
@@ -2,26 +2,58 @@ package callonce
 
 import (
 	"sync"
-
-	"golang.org/x/sync/singleflight"
+	"time"
 )
 
 // Cache holds request-scoped memoized results.
 // Create one per request via WithCache and retrieve it via FromContext.
 type Cache struct {
-	group    singleflight.Group
-	mu       sync.RWMutex
-	store    map[string]any
+	flight   flightShard
+	flights  []flightShard // set by WithShards
+	store    Store
+	mu       sync.RWMutex // guards spans and hidden
 	observer Observer
+	summary  SummaryObserver
+	tracer   Tracer
+	spans    map[StoreKey]Span
+	profile  bool
+	name     string
+	nplusone *nPlusOneDetector
+	budget   *missBudget
+	limits   *fetchLimits
+	stats    cacheStats
+	created  time.Time
+
+	// maxEntries, maxBytes and sizer configure the bounded store; see
+	// WithMaxEntries and WithMaxBytes.
+	maxEntries int
+	maxBytes   int
+	sizer      func(any) int
+
+	// ttl, keyTTL and clock configure expiry; see WithTTL.
+	ttl    time.Duration
+	keyTTL map[string]time.Duration
+	clock  Clock
+	// swr and maxStale configure stale-while-revalidate; see
+	// WithStaleWhileRevalidate.
+	swr      bool
+	maxStale time.Duration
+
+	// parent, level and hidden link a child cache to its parent; see
+	// WithChildCache.
+	parent *Cache
+	level  StoreLevel
+	hidden map[StoreKey]struct{}
+
+	// readOnly is set on snapshots made by Detach.
+	readOnly bool
 }
 
-func (c *Cache) emit(event Event, keyName string, identifier string) {
+func (c *Cache) emit(e EventData) {
+	c.stats.add(&e)
 	if c.observer == nil {
 		return
 	}
-	c.observer.On(EventData{
-		Event:      event,
-		Key:        keyName,
-		Identifier: identifier,
-	})
+	e.Time = time.Now()
+	c.observer.On(e)
 }
@@ -2,19 +2,33 @@ package callonce
 
 import (
 	"context"
+	"time"
 )
 
 type contextKey struct{}
 
 // WithCache returns a child context that carries a new Cache.
 func WithCache(ctx context.Context, opts ...Option) context.Context {
+	return context.WithValue(ctx, contextKey{}, newCache(ctx, opts))
+}
+
+func newCache(ctx context.Context, opts []Option) *Cache {
 	cache := &Cache{
-		store: make(map[string]any),
+		created: time.Now(),
 	}
 	for _, opt := range opts {
 		opt(cache)
 	}
-	return context.WithValue(ctx, contextKey{}, cache)
+	if cache.store == nil {
+		if cache.flights != nil {
+			cache.store = NewShardedStore(len(cache.flights))
+		} else {
+			cache.store = NewMapStore()
+		}
+	}
+	cache.store = cache.bound(cache.store)
+	cache.watch(ctx)
+	return cache
 }
 
 // FromContext retrieves the Cache from ctx, or nil if none is present.
@@ -26,91 +40,70 @@ func FromContext(ctx context.Context) *Cache {
 // Forget removes the given lookups from the cache so that subsequent
 // calls to Get will invoke fn again. It is a no-op if ctx has no Cache.
 func Forget[T any](ctx context.Context, lookups ...Lookup[T]) {
-	c := FromContext(ctx)
+	ForgetFrom(ctx, FromContext(ctx), lookups...)
+}
+
+// ForgetFrom is like Forget but uses c instead of looking the cache up in
+// ctx. ctx is still reported in EventData.Context. It is a no-op if c is
+// nil.
+func ForgetFrom[T any](ctx context.Context, c *Cache, lookups ...Lookup[T]) {
 	if c == nil {
 		return
 	}
 
-	c.mu.Lock()
 	for _, l := range lookups {
-		delete(c.store, l.getFullKey())
+		c.store.Delete(l.storeKey())
+		c.hide(l.storeKey())
+	}
+	forgetSpans(c, lookups)
+
+	for _, l := range lookups {
+		c.emit(EventData{Event: EventForget, Key: l.Key.name, Identifier: l.Identifier, Context: ctx})
 	}
-	c.mu.Unlock()
 }
 
 // Get returns the value for the given lookups, calling fn at most once per
 // cache. When multiple lookups are provided, a cache hit on any one of them
 // returns immediately (OR semantics). On a cache miss fn is called once and
 // the result is stored under every lookup key, so future callers using any
-// of those identifiers will get a cache hit.
+// of those identifiers will get a cache hit. With WithStaleWhileRevalidate,
+// an expired value is returned at once and refreshed in the background.
+//
+// Because that refresh may outlive the call, fn escapes to the heap: a
+// closure that captures variables costs one allocation per call.
 //
 // If ctx has no Cache (WithCache was not called), fn is called directly.
 func Get[T any](ctx context.Context, fn func() (T, error), lookups ...Lookup[T]) (T, error) {
 	if len(lookups) == 0 {
 		return fn()
 	}
+	return GetFrom(ctx, FromContext(ctx), fn, lookups...)
+}
 
-	c := FromContext(ctx)
-	if c == nil {
+// GetFrom is like Get but uses c instead of looking the cache up in ctx,
+// which walks the whole context chain on every call. Resolve the cache once
+// with FromContext and pass it to GetFrom in tight loops. ctx is still used
+// for tracing, fetch limits and EventData.Context.
+//
+// If c is nil, fn is called directly.
+func GetFrom[T any](ctx context.Context, c *Cache, fn func() (T, error), lookups ...Lookup[T]) (T, error) {
+	if len(lookups) == 0 || c == nil {
 		return fn()
 	}
 
 	// Fast path: check if any key is already cached.
-	c.mu.RLock()
-	for _, lookup := range lookups {
-		if v, ok := c.store[lookup.getFullKey()]; ok {
-			c.mu.RUnlock()
-			c.emit(EventHit, lookup.Key.name, lookup.Identifier)
-			if len(lookups) > 1 {
-				c.mu.Lock()
-				for _, l2 := range lookups {
-					c.store[l2.getFullKey()] = v
-				}
-				c.mu.Unlock()
-			}
-			return v.(T), nil
-		}
+	if v, ok := hit(ctx, c, lookups); ok {
+		return v.(T), nil
 	}
-	c.mu.RUnlock()
-
-	// Slow path: singleflight dedup on the first key.
-	val, err, shared := c.group.Do(lookups[0].Key.name+delimiter+lookups[0].Identifier, func() (any, error) {
-		// Double-check: another goroutine may have cached while we waited.
-		c.mu.RLock()
-		for _, l := range lookups {
-			if v, ok := c.store[l.getFullKey()]; ok {
-				c.mu.RUnlock()
-				c.emit(EventHit, l.Key.name, l.Identifier)
-				return v, nil
-			}
-		}
-		c.mu.RUnlock()
-
-		c.emit(EventMiss, lookups[0].Key.name, lookups[0].Identifier)
-		result, err := fn()
-		if err != nil {
-			return result, err
-		}
-
-		// Store under ALL keys.
-		c.mu.Lock()
-		for _, l := range lookups {
-			c.store[l.getFullKey()] = result
+	if c.swr {
+		if v, ok := stale(ctx, c, fn, lookups); ok {
+			return v.(T), nil
 		}
-		c.mu.Unlock()
-
-		return result, nil
-	})
-
-	// Shared callers piggyback on the in-flight result.
-	if shared {
-		c.emit(EventDedup, lookups[0].Key.name, lookups[0].Identifier)
 	}
-
-	if err != nil {
-		var zero T
-		return zero, err
+	if c.readOnly {
+		return fn()
 	}
 
-	return val.(T), nil
+	// Slow path: run fn, or join the call already running for the first key.
+	return load(ctx, c, fn, lookups)
 }
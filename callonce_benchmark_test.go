@@ -64,6 +64,41 @@ func BenchmarkErrorNotCached(b *testing.B) {
 	}
 }
 
+// deepContext wraps a cached context in depth unrelated values, like a deep
+// middleware stack would.
+func deepContext(depth int) context.Context {
+	ctx := callonce.WithCache(context.Background())
+	for i := 0; i < depth; i++ {
+		ctx = context.WithValue(ctx, struct{ i int }{i}, i)
+	}
+	return ctx
+}
+
+// Cache hit through Get, which looks the cache up in a 32-deep context.
+func BenchmarkDeepContext_Get(b *testing.B) {
+	ctx := deepContext(32)
+	callonce.Get(ctx, func() (string, error) { return "v", nil }, callonce.L(benchKey, "1"))
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		callonce.Get(ctx, func() (string, error) { return "v", nil }, callonce.L(benchKey, "1"))
+	}
+}
+
+// Same hit through GetFrom with the cache resolved once up front.
+func BenchmarkDeepContext_GetFrom(b *testing.B) {
+	ctx := deepContext(32)
+	c := callonce.FromContext(ctx)
+	callonce.GetFrom(ctx, c, func() (string, error) { return "v", nil }, callonce.L(benchKey, "1"))
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		callonce.GetFrom(ctx, c, func() (string, error) { return "v", nil }, callonce.L(benchKey, "1"))
+	}
+}
+
 // ---------------------------------------------------------------------------
 // Concurrent benchmarks: measure throughput under contention.
 // ---------------------------------------------------------------------------
@@ -108,6 +143,29 @@ func BenchmarkConcurrent_UniqueKeys(b *testing.B) {
 	}
 }
 
+// Same as UniqueKeys but with the store and in-flight calls sharded by
+// WithShards, to compare with the default single map.
+func BenchmarkConcurrent_UniqueKeys_Sharded(b *testing.B) {
+	ids := make([]string, 1000)
+	for i := range ids {
+		ids[i] = fmt.Sprintf("%d", i)
+	}
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		ctx := callonce.WithCache(context.Background(), callonce.WithShards(32))
+		var wg sync.WaitGroup
+		wg.Add(1000)
+		for j := 0; j < 1000; j++ {
+			go func(j int) {
+				defer wg.Done()
+				callonce.Get(ctx, func() (string, error) { return "v", nil }, callonce.L(benchKey, ids[j]))
+			}(j)
+		}
+		wg.Wait()
+	}
+}
+
 // 1000 goroutines sharing 100 keys. Realistic mix of hits and dedup.
 func BenchmarkConcurrent_MixedKeys(b *testing.B) {
 	ids := make([]string, 100)
@@ -147,7 +147,7 @@ func TestGetPanicPropagates(t *testing.T) {
 			if r == nil {
 				t.Fatal("expected panic, got none")
 			}
-			// singleflight wraps panics; check the string representation.
+			// Panics are wrapped with a stack trace; check the string representation.
 			if s := fmt.Sprint(r); !strings.Contains(s, "kaboom") {
 				t.Fatalf("got panic %v, want it to contain %q", r, "kaboom")
 			}
@@ -574,3 +574,45 @@ func TestGetORBackfillsOnPartialHit(t *testing.T) {
 		t.Fatalf("fn called %d times, want 1", n)
 	}
 }
+
+func TestGetFromSharesCacheWithGet(t *testing.T) {
+	ctx := callonce.WithCache(context.Background())
+	c := callonce.FromContext(ctx)
+
+	var calls atomic.Int32
+	fn := func() (string, error) {
+		calls.Add(1)
+		return "v", nil
+	}
+
+	callonce.GetFrom(ctx, c, fn, callonce.L(testKey, "1"))
+	v, err := callonce.Get(ctx, fn, callonce.L(testKey, "1"))
+	if err != nil || v != "v" {
+		t.Fatalf("got %q, %v; want %q, nil", v, err, "v")
+	}
+	if calls.Load() != 1 {
+		t.Fatalf("fn called %d times, want 1", calls.Load())
+	}
+
+	callonce.ForgetFrom(ctx, c, callonce.L(testKey, "1"))
+	callonce.GetFrom(ctx, c, fn, callonce.L(testKey, "1"))
+	if calls.Load() != 2 {
+		t.Fatalf("fn called %d times after ForgetFrom, want 2", calls.Load())
+	}
+}
+
+func TestGetFromNilCache(t *testing.T) {
+	var calls int
+	for i := 0; i < 2; i++ {
+		callonce.GetFrom(context.Background(), nil, func() (string, error) {
+			calls++
+			return "v", nil
+		}, callonce.L(testKey, "1"))
+	}
+	if calls != 2 {
+		t.Fatalf("fn called %d times, want 2", calls)
+	}
+
+	// Should not panic.
+	callonce.ForgetFrom(context.Background(), nil, callonce.L(testKey, "1"))
+}
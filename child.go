@@ -0,0 +1,136 @@
+package callonce
+
+import "context"
+
+// StoreLevel selects which cache in a chain of child caches new values are
+// stored in. See WithStoreLevel.
+type StoreLevel int
+
+const (
+	// StoreLocal stores values in the cache that Get was called with. It
+	// is the default.
+	StoreLocal StoreLevel = iota
+	// StoreParent stores values in the parent cache, so that siblings see
+	// them too.
+	StoreParent
+	// StoreRoot stores values in the outermost cache of the chain.
+	StoreRoot
+)
+
+// WithStoreLevel sets where a child cache stores the values it fetches.
+// It has no effect on a cache without a parent.
+func WithStoreLevel(level StoreLevel) Option {
+	return func(cache *Cache) {
+		cache.level = level
+	}
+}
+
+// WithChildCache returns a child context that carries a new Cache whose
+// parent is the Cache in ctx. Get checks the child first and then each of
+// its parents, and stores fetched values at the level set by
+// WithStoreLevel. Concurrent misses for the same key are deduplicated
+// across every cache that stores into the same cache, so siblings using
+// StoreParent call fn once between them.
+//
+// Forget on the child removes its own values and hides its parents' values
+// from it without touching them, so the child re-fetches them on the next
+// Get.
+//
+// The child shares its parent's miss budget, fetch limits and N+1
+// detector, so fetches through the child count against the request's
+// limits; the child's own options for these apply only where the parent
+// has none. Other options are not inherited. If ctx has no Cache,
+// WithChildCache is equivalent to WithCache.
+func WithChildCache(ctx context.Context, opts ...Option) context.Context {
+	cache := newCache(ctx, opts)
+	if p := FromContext(ctx); p != nil {
+		cache.parent = p
+		if p.budget != nil {
+			cache.budget = p.budget
+		}
+		if p.limits != nil {
+			cache.limits = p.limits
+		}
+		if p.nplusone != nil {
+			cache.nplusone = p.nplusone
+		}
+	}
+	return context.WithValue(ctx, contextKey{}, cache)
+}
+
+// lookup returns the stored value for key from c or the nearest parent
+// that has it, along with the cache that holds it.
+func (c *Cache) lookup(key StoreKey) (stored any, owner *Cache, ok bool) {
+	for owner = c; owner != nil; owner = owner.parent {
+		if stored, ok = owner.store.Get(key); ok {
+			return stored, owner, true
+		}
+		if owner.parent == nil || owner.isHidden(key) {
+			break
+		}
+	}
+	return nil, nil, false
+}
+
+// target returns the cache that new values are stored in: the cache at
+// c's store level, or the outermost writable cache below it if that one is
+// a read-only snapshot made by Detach.
+func (c *Cache) target() *Cache {
+	if c.parent == nil || c.level == StoreLocal {
+		return c
+	}
+	t := c
+	for cc := c.parent; cc != nil && !cc.readOnly; cc = cc.parent {
+		t = cc
+		if c.level == StoreParent {
+			break
+		}
+	}
+	return t
+}
+
+// put stores a stored value under key at c's store level and makes it
+// visible to c again if Forget had hidden it. It does nothing if c is
+// read-only.
+func (c *Cache) put(key StoreKey, stored any) {
+	t := c.target()
+	if t.readOnly {
+		return
+	}
+	t.store.Set(key, stored)
+	for cc := c; ; cc = cc.parent {
+		cc.unhide(key)
+		if cc == t {
+			return
+		}
+	}
+}
+
+// hide masks the parents' value for key from c.
+func (c *Cache) hide(key StoreKey) {
+	if c.parent == nil {
+		return
+	}
+	c.mu.Lock()
+	if c.hidden == nil {
+		c.hidden = make(map[StoreKey]struct{})
+	}
+	c.hidden[key] = struct{}{}
+	c.mu.Unlock()
+}
+
+func (c *Cache) unhide(key StoreKey) {
+	if c.parent == nil {
+		return
+	}
+	c.mu.Lock()
+	delete(c.hidden, key)
+	c.mu.Unlock()
+}
+
+func (c *Cache) isHidden(key StoreKey) bool {
+	c.mu.RLock()
+	_, ok := c.hidden[key]
+	c.mu.RUnlock()
+	return ok
+}
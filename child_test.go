@@ -0,0 +1,128 @@
+package callonce_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	callonce "github.com/probablyarth/callonce-go"
+)
+
+func TestChildCacheSeesParentValues(t *testing.T) {
+	parent := callonce.WithCache(context.Background())
+	callonce.Get(parent, func() (string, error) { return "request", nil }, callonce.L(testKey, "1"))
+
+	child := callonce.WithChildCache(parent)
+	v, _ := callonce.Get(child, func() (string, error) { return "child", nil }, callonce.L(testKey, "1"))
+	if v != "request" {
+		t.Fatalf("got %q, want parent value %q", v, "request")
+	}
+
+	// By default the child keeps its own values to itself.
+	callonce.Get(child, func() (string, error) { return "child", nil }, callonce.L(testKey, "2"))
+	v, _ = callonce.Get(parent, func() (string, error) { return "parent", nil }, callonce.L(testKey, "2"))
+	if v != "parent" {
+		t.Fatalf("got %q, want %q", v, "parent")
+	}
+}
+
+func TestChildCacheStoreLevels(t *testing.T) {
+	tests := []struct {
+		level    callonce.StoreLevel
+		midSees  bool
+		rootSees bool
+	}{
+		{callonce.StoreLocal, false, false},
+		{callonce.StoreParent, true, false},
+		{callonce.StoreRoot, true, true},
+	}
+	for _, tt := range tests {
+		root := callonce.WithCache(context.Background())
+		mid := callonce.WithChildCache(root)
+		leaf := callonce.WithChildCache(mid, callonce.WithStoreLevel(tt.level))
+		callonce.Get(leaf, func() (string, error) { return "leaf", nil }, callonce.L(testKey, "1"))
+
+		v, _ := callonce.Get(mid, func() (string, error) { return "miss", nil }, callonce.L(testKey, "1"))
+		if (v == "leaf") != tt.midSees {
+			t.Errorf("level %d: mid got %q", tt.level, v)
+		}
+		v, _ = callonce.Get(root, func() (string, error) { return "miss", nil }, callonce.L(testKey, "1"))
+		if (v == "leaf") != tt.rootSees {
+			t.Errorf("level %d: root got %q", tt.level, v)
+		}
+	}
+}
+
+func TestChildCacheSharesInFlightCalls(t *testing.T) {
+	parent := callonce.WithCache(context.Background())
+	ctxs := []context.Context{
+		parent,
+		callonce.WithChildCache(parent, callonce.WithStoreLevel(callonce.StoreParent)),
+		callonce.WithChildCache(parent, callonce.WithStoreLevel(callonce.StoreParent)),
+	}
+
+	var calls atomic.Int32
+	var wg sync.WaitGroup
+	for _, ctx := range ctxs {
+		wg.Add(1)
+		go func(ctx context.Context) {
+			defer wg.Done()
+			callonce.Get(ctx, func() (string, error) {
+				calls.Add(1)
+				time.Sleep(50 * time.Millisecond)
+				return "v", nil
+			}, callonce.L(testKey, "1"))
+		}(ctx)
+	}
+	wg.Wait()
+
+	if n := calls.Load(); n != 1 {
+		t.Fatalf("fn called %d times, want 1", n)
+	}
+}
+
+func TestChildCacheSharesParentBudget(t *testing.T) {
+	parent := callonce.WithCache(context.Background(), callonce.WithMissBudget(1))
+	callonce.Get(parent, func() (string, error) { return "v", nil }, callonce.L(testKey, "1"))
+
+	child := callonce.WithChildCache(parent)
+	_, err := callonce.Get(child, func() (string, error) { return "v", nil }, callonce.L(testKey, "2"))
+	if !errors.Is(err, callonce.ErrBudgetExceeded) {
+		t.Fatalf("got %v, want the parent's budget to apply to the child", err)
+	}
+}
+
+func TestChildCacheForgetDoesNotAffectParent(t *testing.T) {
+	parent := callonce.WithCache(context.Background())
+	callonce.Get(parent, func() (string, error) { return "old", nil }, callonce.L(testKey, "1"))
+
+	child := callonce.WithChildCache(parent)
+	callonce.Forget(child, callonce.L(testKey, "1"))
+
+	v, _ := callonce.Get(child, func() (string, error) { return "new", nil }, callonce.L(testKey, "1"))
+	if v != "new" {
+		t.Fatalf("child got %q after Forget, want %q", v, "new")
+	}
+	v, _ = callonce.Get(parent, func() (string, error) { return "miss", nil }, callonce.L(testKey, "1"))
+	if v != "old" {
+		t.Fatalf("parent got %q, want %q", v, "old")
+	}
+}
+
+func TestChildCacheWithoutParent(t *testing.T) {
+	ctx := callonce.WithChildCache(context.Background(), callonce.WithStoreLevel(callonce.StoreRoot))
+
+	calls := 0
+	for i := 0; i < 2; i++ {
+		callonce.Get(ctx, func() (string, error) {
+			calls++
+			return "v", nil
+		}, callonce.L(testKey, "1"))
+	}
+	if calls != 1 {
+		t.Fatalf("fn called %d times, want 1", calls)
+	}
+}
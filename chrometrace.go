@@ -0,0 +1,227 @@
+package callonce
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ChromeTraceRecorder is an Observer that records one Cache's activity
+// and exports it in the Chrome Trace Event Format, which can be loaded in
+// chrome://tracing or https://ui.perfetto.dev.
+//
+// Each fn execution becomes a slice on its own lane, dedup waiters become
+// "wait" slices with a flow arrow from the leader's slice, and hits become
+// instant events. Goroutine identity is not recorded, so lanes are
+// assigned to avoid overlaps rather than per goroutine. A dedup whose
+// leader never called fn, for example because a miss budget was
+// exhausted, has no fetch to wait on and is left out.
+//
+// Attach one recorder per Cache.
+type ChromeTraceRecorder struct {
+	mu      sync.Mutex
+	fetches []traceSlice
+	waits   []traceWait
+	hits    []traceInstant
+	// last maps key+identifier to the index of its most recent fetch.
+	last map[string]int
+}
+
+type traceSlice struct {
+	key, identifier string
+	start, end      time.Time
+	err             error
+}
+
+type traceWait struct {
+	key, identifier string
+	leader          int
+	start, end      time.Time
+}
+
+type traceInstant struct {
+	key, identifier string
+	at              time.Time
+}
+
+// NewChromeTraceRecorder returns an empty ChromeTraceRecorder.
+func NewChromeTraceRecorder() *ChromeTraceRecorder {
+	return &ChromeTraceRecorder{last: make(map[string]int)}
+}
+
+// On implements Observer.
+func (r *ChromeTraceRecorder) On(e EventData) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	id := e.Key + delimiter + e.Identifier
+	switch e.Event {
+	case EventMiss:
+		r.last[id] = len(r.fetches)
+		r.fetches = append(r.fetches, traceSlice{
+			key:        e.Key,
+			identifier: e.Identifier,
+			start:      e.Time.Add(-e.Duration),
+			end:        e.Time,
+			err:        e.Err,
+		})
+	case EventDedup:
+		// The leader's miss is always observed before its waiters' dedups.
+		// A fetch that ended before the waiter joined belongs to an
+		// earlier call, and the leader of this one failed before fn ran.
+		start := e.Time.Add(-e.Duration)
+		if leader, ok := r.last[id]; ok && !r.fetches[leader].end.Before(start) {
+			r.waits = append(r.waits, traceWait{key: e.Key, identifier: e.Identifier, leader: leader, start: start, end: e.Time})
+		}
+	case EventHit:
+		r.hits = append(r.hits, traceInstant{key: e.Key, identifier: e.Identifier, at: e.Time})
+	}
+}
+
+// traceEvent is a single entry of the Chrome Trace Event Format.
+type traceEvent struct {
+	Name  string         `json:"name"`
+	Cat   string         `json:"cat,omitempty"`
+	Ph    string         `json:"ph"`
+	Ts    float64        `json:"ts"`
+	Dur   float64        `json:"dur,omitempty"`
+	Pid   int            `json:"pid"`
+	Tid   int            `json:"tid"`
+	ID    int            `json:"id,omitempty"`
+	Scope string         `json:"s,omitempty"`
+	BP    string         `json:"bp,omitempty"`
+	Args  map[string]any `json:"args,omitempty"`
+}
+
+// WriteTo writes the recorded activity as a Chrome Trace Event Format
+// JSON object to w. Timestamps are relative to the first recorded event.
+func (r *ChromeTraceRecorder) WriteTo(w io.Writer) (int64, error) {
+	r.mu.Lock()
+	events := r.traceEvents()
+	r.mu.Unlock()
+
+	b, err := json.Marshal(struct {
+		TraceEvents     []traceEvent `json:"traceEvents"`
+		DisplayTimeUnit string       `json:"displayTimeUnit"`
+	}{events, "ms"})
+	if err != nil {
+		return 0, err
+	}
+	n, err := w.Write(b)
+	return int64(n), err
+}
+
+const (
+	tracePid    = 1
+	traceHitTid = 0
+)
+
+func (r *ChromeTraceRecorder) traceEvents() []traceEvent {
+	origin := r.origin()
+	us := func(t time.Time) float64 {
+		return float64(t.Sub(origin).Nanoseconds()) / 1e3
+	}
+
+	// Fetch and wait slices share lanes, assigned greedily by start time.
+	type laneItem struct {
+		start, end time.Time
+		fetch      int // index into r.fetches, or -1
+		wait       int // index into r.waits, or -1
+	}
+	items := make([]laneItem, 0, len(r.fetches)+len(r.waits))
+	for i, f := range r.fetches {
+		items = append(items, laneItem{start: f.start, end: f.end, fetch: i, wait: -1})
+	}
+	for i, wt := range r.waits {
+		items = append(items, laneItem{start: wt.start, end: wt.end, fetch: -1, wait: i})
+	}
+	sort.SliceStable(items, func(i, j int) bool { return items[i].start.Before(items[j].start) })
+
+	var laneEnds []time.Time
+	fetchTid := make([]int, len(r.fetches))
+	waitTid := make([]int, len(r.waits))
+	for _, it := range items {
+		lane := -1
+		for l, end := range laneEnds {
+			if !end.After(it.start) {
+				lane = l
+				break
+			}
+		}
+		if lane < 0 {
+			lane = len(laneEnds)
+			laneEnds = append(laneEnds, time.Time{})
+		}
+		laneEnds[lane] = it.end
+		if it.fetch >= 0 {
+			fetchTid[it.fetch] = lane + 1
+		} else {
+			waitTid[it.wait] = lane + 1
+		}
+	}
+
+	events := []traceEvent{
+		{Name: "process_name", Ph: "M", Pid: tracePid, Args: map[string]any{"name": "callonce"}},
+		{Name: "thread_name", Ph: "M", Pid: tracePid, Tid: traceHitTid, Args: map[string]any{"name": "hits"}},
+	}
+	for i, f := range r.fetches {
+		args := map[string]any{"identifier": f.identifier}
+		if f.err != nil {
+			args["error"] = f.err.Error()
+		}
+		events = append(events, traceEvent{
+			Name: f.key, Cat: "fetch", Ph: "X",
+			Ts: us(f.start), Dur: us(f.end) - us(f.start),
+			Pid: tracePid, Tid: fetchTid[i], Args: args,
+		})
+	}
+	for i, wt := range r.waits {
+		leader := r.fetches[wt.leader]
+		start := us(wt.start)
+		events = append(events,
+			traceEvent{
+				Name: "wait " + wt.key, Cat: "dedup", Ph: "X",
+				Ts: start, Dur: us(wt.end) - start,
+				Pid: tracePid, Tid: waitTid[i],
+				Args: map[string]any{"identifier": wt.identifier},
+			},
+			// The flow starts in the middle of the overlap with the
+			// leader's slice, so it binds to it unambiguously, and ends on
+			// the waiter's slice.
+			traceEvent{
+				Name: "dedup", Cat: "dedup", Ph: "s", ID: i + 1,
+				Ts: (max(start, us(leader.start)) + us(leader.end)) / 2, Pid: tracePid, Tid: fetchTid[wt.leader],
+			},
+			traceEvent{
+				Name: "dedup", Cat: "dedup", Ph: "f", BP: "e", ID: i + 1,
+				Ts: us(wt.end), Pid: tracePid, Tid: waitTid[i],
+			},
+		)
+	}
+	for _, h := range r.hits {
+		events = append(events, traceEvent{
+			Name: h.key, Cat: "hit", Ph: "i", Scope: "t",
+			Ts: us(h.at), Pid: tracePid, Tid: traceHitTid,
+			Args: map[string]any{"identifier": h.identifier},
+		})
+	}
+	return events
+}
+
+func (r *ChromeTraceRecorder) origin() time.Time {
+	var origin time.Time
+	earlier := func(t time.Time) {
+		if origin.IsZero() || t.Before(origin) {
+			origin = t
+		}
+	}
+	for _, f := range r.fetches {
+		earlier(f.start)
+	}
+	for _, h := range r.hits {
+		earlier(h.at)
+	}
+	return origin
+}
@@ -0,0 +1,153 @@
+package callonce_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	callonce "github.com/probablyarth/callonce-go"
+)
+
+type chromeEvent struct {
+	Name string         `json:"name"`
+	Cat  string         `json:"cat"`
+	Ph   string         `json:"ph"`
+	Ts   float64        `json:"ts"`
+	Dur  float64        `json:"dur"`
+	Tid  int            `json:"tid"`
+	ID   int            `json:"id"`
+	Args map[string]any `json:"args"`
+}
+
+func TestChromeTraceRecorder(t *testing.T) {
+	rec := callonce.NewChromeTraceRecorder()
+	ctx := callonce.WithCache(context.Background(), callonce.WithObserver(rec))
+
+	const waiters = 3
+	release := make(chan struct{})
+	started := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(waiters + 1)
+	for i := 0; i < waiters+1; i++ {
+		go func() {
+			defer wg.Done()
+			callonce.Get(ctx, func() (string, error) {
+				close(started)
+				<-release
+				return "v", nil
+			}, callonce.L(testKey, "1"))
+		}()
+	}
+	<-started
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	callonce.Get(ctx, func() (string, error) { return "v", nil }, callonce.L(testKey, "1"))
+	callonce.Get(ctx, func() (string, error) { return "w", nil }, callonce.L(testKey, "2"))
+
+	var buf bytes.Buffer
+	if _, err := rec.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var out struct {
+		TraceEvents []chromeEvent `json:"traceEvents"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &out); err != nil {
+		t.Fatalf("invalid trace JSON: %v", err)
+	}
+
+	byPh := map[string][]chromeEvent{}
+	for _, e := range out.TraceEvents {
+		byPh[e.Ph+"/"+e.Cat] = append(byPh[e.Ph+"/"+e.Cat], e)
+	}
+	fetches := byPh["X/fetch"]
+	if len(fetches) != 2 {
+		t.Fatalf("got %d fetch slices, want 2", len(fetches))
+	}
+	if fetches[0].Dur < 10e3 {
+		t.Fatalf("leader slice lasted %vµs, want at least 10ms", fetches[0].Dur)
+	}
+	waits, starts, ends := byPh["X/dedup"], byPh["s/dedup"], byPh["f/dedup"]
+	dedups := len(waits)
+	if dedups == 0 || len(starts) != dedups || len(ends) != dedups {
+		t.Fatalf("got %d waits, %d flow starts, %d flow ends", dedups, len(starts), len(ends))
+	}
+	hits := byPh["i/hit"]
+	if dedups+len(hits) != waiters+1 {
+		t.Fatalf("got %d dedups + %d hits, want %d", dedups, len(hits), waiters+1)
+	}
+	for _, s := range starts {
+		if s.Tid != fetches[0].Tid {
+			t.Fatalf("flow starts on lane %d, want leader lane %d", s.Tid, fetches[0].Tid)
+		}
+	}
+	for _, w := range waits {
+		if w.Tid == fetches[0].Tid {
+			t.Fatal("wait slice shares the leader's lane")
+		}
+	}
+}
+
+func TestChromeTraceRecorderSkipsFailedLeader(t *testing.T) {
+	rec := callonce.NewChromeTraceRecorder()
+	ctx := callonce.WithCache(context.Background(),
+		callonce.WithObserver(rec),
+		callonce.WithMaxConcurrentFetches(1),
+	)
+
+	// An earlier, failed fetch of the same identifier.
+	callonce.Get(ctx, func() (string, error) { return "", errors.New("fail") }, callonce.L(testKey, "1"))
+
+	// Hold the only fetch slot so that the next leader for "1" waits for it.
+	release := make(chan struct{})
+	holding := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(3)
+	go func() {
+		defer wg.Done()
+		callonce.Get(ctx, func() (string, error) {
+			close(holding)
+			<-release
+			return "v", nil
+		}, callonce.L(testKey, "busy"))
+	}()
+	<-holding
+
+	leaderCtx, cancel := context.WithCancel(ctx)
+	go func() {
+		defer wg.Done()
+		callonce.Get(leaderCtx, func() (string, error) { return "v", nil }, callonce.L(testKey, "1"))
+	}()
+	time.Sleep(10 * time.Millisecond)
+	go func() {
+		defer wg.Done()
+		callonce.Get(ctx, func() (string, error) { return "v", nil }, callonce.L(testKey, "1"))
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	// The leader gives up before calling fn and its waiter shares the error.
+	cancel()
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	var buf bytes.Buffer
+	if _, err := rec.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var out struct {
+		TraceEvents []chromeEvent `json:"traceEvents"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &out); err != nil {
+		t.Fatalf("invalid trace JSON: %v", err)
+	}
+	for _, e := range out.TraceEvents {
+		if e.Cat == "dedup" {
+			t.Fatalf("got %+v tied to an earlier fetch", e)
+		}
+	}
+}
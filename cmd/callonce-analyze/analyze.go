@@ -0,0 +1,327 @@
+package main
+
+import (
+	"bufio"
+	"container/heap"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	callonce "github.com/probablyarth/callonce-go"
+)
+
+type counts struct {
+	hits   int
+	misses int
+	dedups int
+	stale  int
+	errors int
+	fnTime time.Duration
+}
+
+func (c *counts) add(r *callonce.Record) {
+	switch r.Event {
+	case "hit":
+		c.hits++
+	case "miss":
+		c.misses++
+		c.fnTime += r.Duration
+		if r.Error != "" {
+			c.errors++
+		}
+	case "dedup":
+		c.dedups++
+	case "stale":
+		c.stale++
+	}
+}
+
+// served is the number of lookups served from the cache, including stale
+// values returned while being refreshed.
+func (c *counts) served() int {
+	return c.hits + c.dedups + c.stale
+}
+
+// hitRatio is the share of lookups served without waiting for fn.
+func (c *counts) hitRatio() float64 {
+	total := c.served() + c.misses
+	if total == 0 {
+		return 0
+	}
+	return float64(c.served()) / float64(total)
+}
+
+// saved estimates the fn time avoided, using the average miss duration.
+// Stale hits are left out: their refresh still calls fn.
+func (c *counts) saved() time.Duration {
+	if c.misses == 0 {
+		return 0
+	}
+	return c.fnTime / time.Duration(c.misses) * time.Duration(c.hits+c.dedups)
+}
+
+type requestKey struct {
+	request string
+	key     string
+}
+
+type nPlusOne struct {
+	key      string
+	route    string
+	requests int
+	maxIDs   int
+}
+
+// fetchHeap is a min-heap of misses by duration, so that the shortest of
+// the slowest fetches seen so far is at the root.
+type fetchHeap []fetch
+
+type fetch struct {
+	seq int
+	callonce.Record
+}
+
+func (h fetchHeap) Len() int { return len(h) }
+func (h fetchHeap) Less(i, j int) bool {
+	if h[i].Duration != h[j].Duration {
+		return h[i].Duration < h[j].Duration
+	}
+	return h[i].seq > h[j].seq // earlier records win ties
+}
+func (h fetchHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+func (h *fetchHeap) Push(x any)   { *h = append(*h, x.(fetch)) }
+func (h *fetchHeap) Pop() any {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
+
+type analyzer struct {
+	threshold int
+	top       int
+	records   int
+	fetches   int
+	routes    map[string]*counts
+	keys      map[string]*counts
+	// slow holds the top slowest misses seen so far.
+	slow fetchHeap
+	// missed holds the distinct identifiers missed per request and key
+	// until they reach the N+1 threshold. The request is then counted in
+	// found and its identifiers dropped; flagged tracks its further misses.
+	missed  map[requestKey]map[string]struct{}
+	flagged map[requestKey]*flaggedRequest
+	found   map[nPlusOneGroup]*nPlusOne
+	// routeOf remembers the route of each request.
+	routeOf map[string]string
+}
+
+type nPlusOneGroup struct{ key, route string }
+
+type flaggedRequest struct {
+	group *nPlusOne
+	ids   int
+}
+
+func newAnalyzer(threshold, top int) *analyzer {
+	return &analyzer{
+		threshold: threshold,
+		top:       top,
+		routes:    make(map[string]*counts),
+		keys:      make(map[string]*counts),
+		missed:    make(map[requestKey]map[string]struct{}),
+		flagged:   make(map[requestKey]*flaggedRequest),
+		found:     make(map[nPlusOneGroup]*nPlusOne),
+		routeOf:   make(map[string]string),
+	}
+}
+
+// read consumes a JSON Lines stream of callonce.Record values.
+func (a *analyzer) read(r io.Reader) error {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64<<10), 1<<20)
+	line := 0
+	for sc.Scan() {
+		line++
+		if len(sc.Bytes()) == 0 {
+			continue
+		}
+		var rec callonce.Record
+		if err := json.Unmarshal(sc.Bytes(), &rec); err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+		a.add(&rec)
+	}
+	return sc.Err()
+}
+
+func (a *analyzer) add(r *callonce.Record) {
+	a.records++
+	counter(a.keys, r.Key).add(r)
+	if r.Route != "" {
+		counter(a.routes, r.Route).add(r)
+	}
+	if r.Event != "miss" {
+		return
+	}
+
+	a.fetches++
+	if a.top > 0 {
+		f := fetch{seq: a.fetches, Record: *r}
+		if len(a.slow) < a.top {
+			heap.Push(&a.slow, f)
+		} else if f.Duration > a.slow[0].Duration {
+			a.slow[0] = f
+			heap.Fix(&a.slow, 0)
+		}
+	}
+	if r.RequestID == "" {
+		return
+	}
+	if r.Route != "" {
+		a.routeOf[r.RequestID] = r.Route
+	}
+	a.missedID(requestKey{request: r.RequestID, key: r.Key}, r.Identifier)
+}
+
+// missedID records a miss of identifier within a request. Once a request
+// reaches the N+1 threshold for a key it is counted and its identifiers
+// are dropped, so that only requests below the threshold hold sets;
+// further misses are counted without telling identifiers apart.
+func (a *analyzer) missedID(rk requestKey, identifier string) {
+	if f, ok := a.flagged[rk]; ok {
+		f.ids++
+		f.group.maxIDs = max(f.group.maxIDs, f.ids)
+		return
+	}
+
+	ids, ok := a.missed[rk]
+	if !ok {
+		ids = make(map[string]struct{})
+		a.missed[rk] = ids
+	}
+	ids[identifier] = struct{}{}
+	if len(ids) < a.threshold {
+		return
+	}
+
+	g := nPlusOneGroup{key: rk.key, route: a.routeOf[rk.request]}
+	p, ok := a.found[g]
+	if !ok {
+		p = &nPlusOne{key: g.key, route: g.route}
+		a.found[g] = p
+	}
+	p.requests++
+	p.maxIDs = max(p.maxIDs, len(ids))
+	a.flagged[rk] = &flaggedRequest{group: p, ids: len(ids)}
+	delete(a.missed, rk)
+}
+
+func counter(m map[string]*counts, name string) *counts {
+	c, ok := m[name]
+	if !ok {
+		c = new(counts)
+		m[name] = c
+	}
+	return c
+}
+
+// slowest returns up to top misses ordered by descending duration.
+func (a *analyzer) slowest() []callonce.Record {
+	slow := append(fetchHeap(nil), a.slow...)
+	sort.Slice(slow, func(i, j int) bool { return slow.Less(j, i) })
+	out := make([]callonce.Record, len(slow))
+	for i, f := range slow {
+		out[i] = f.Record
+	}
+	return out
+}
+
+// nPlusOnes returns the requests that missed at least threshold distinct
+// identifiers of one key, grouped by key and route.
+func (a *analyzer) nPlusOnes() []nPlusOne {
+	out := make([]nPlusOne, 0, len(a.found))
+	for _, p := range a.found {
+		out = append(out, *p)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].requests != out[j].requests {
+			return out[i].requests > out[j].requests
+		}
+		return out[i].key+out[i].route < out[j].key+out[j].route
+	})
+	return out
+}
+
+// neverHit returns keys that were fetched but never served from cache.
+func (a *analyzer) neverHit() []string {
+	var out []string
+	for name, c := range a.keys {
+		if c.misses > 0 && c.served() == 0 {
+			out = append(out, name)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+func (a *analyzer) report(w io.Writer) {
+	fmt.Fprintf(w, "%d records, %d fetches\n", a.records, a.fetches)
+
+	if len(a.routes) > 0 {
+		fmt.Fprintln(w, "\nRoutes")
+		writeCounts(w, "ROUTE", a.routes)
+	}
+
+	if len(a.keys) > 0 {
+		fmt.Fprintln(w, "\nKeys")
+		writeCounts(w, "KEY", a.keys)
+	}
+
+	if slow := a.slowest(); len(slow) > 0 {
+		fmt.Fprintln(w, "\nSlowest fetches")
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "DURATION\tKEY\tIDENTIFIER\tREQUEST\tERROR")
+		for _, r := range slow {
+			fmt.Fprintf(tw, "%v\t%s\t%s\t%s\t%s\n", r.Duration, r.Key, r.Identifier, r.RequestID, r.Error)
+		}
+		tw.Flush()
+	}
+
+	if ps := a.nPlusOnes(); len(ps) > 0 {
+		fmt.Fprintf(w, "\nN+1 patterns (>= %d distinct identifiers per request)\n", a.threshold)
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "KEY\tROUTE\tREQUESTS\tMAX IDS")
+		for _, p := range ps {
+			fmt.Fprintf(tw, "%s\t%s\t%d\t%d\n", p.key, p.route, p.requests, p.maxIDs)
+		}
+		tw.Flush()
+	}
+
+	if keys := a.neverHit(); len(keys) > 0 {
+		fmt.Fprintln(w, "\nNever hit (caching has no effect)")
+		for _, k := range keys {
+			fmt.Fprintf(w, "  %s\n", k)
+		}
+	}
+}
+
+func writeCounts(w io.Writer, label string, m map[string]*counts) {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "%s\tHITS\tMISSES\tDEDUPS\tSTALE\tERRORS\tHIT RATIO\tFN TIME\tSAVED\n", label)
+	for _, name := range names {
+		c := m[name]
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%d\t%d\t%.1f%%\t%v\t%v\n",
+			name, c.hits, c.misses, c.dedups, c.stale, c.errors, 100*c.hitRatio(), c.fnTime, c.saved())
+	}
+	tw.Flush()
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	callonce "github.com/probablyarth/callonce-go"
+)
+
+const sampleLog = `{"time":"2026-01-01T00:00:00Z","request_id":"r1","route":"GET /feed","event":"miss","key":"*User:user","identifier":"1","duration_ns":3000000}
+{"time":"2026-01-01T00:00:00Z","request_id":"r1","route":"GET /feed","event":"miss","key":"*User:user","identifier":"2","duration_ns":1000000}
+{"time":"2026-01-01T00:00:00Z","request_id":"r1","route":"GET /feed","event":"miss","key":"*User:user","identifier":"3","duration_ns":2000000}
+{"time":"2026-01-01T00:00:00Z","request_id":"r1","route":"GET /feed","event":"hit","key":"*User:user","identifier":"1"}
+{"time":"2026-01-01T00:00:00Z","request_id":"r1","route":"GET /feed","event":"dedup","key":"*User:user","identifier":"2"}
+
+{"time":"2026-01-01T00:00:00Z","request_id":"r2","route":"GET /me","event":"miss","key":"*Org:org","identifier":"a","duration_ns":5000000,"error":"timeout"}
+`
+
+func TestAnalyzer(t *testing.T) {
+	a := newAnalyzer(3, 2)
+	if err := a.read(strings.NewReader(sampleLog)); err != nil {
+		t.Fatal(err)
+	}
+
+	user := a.keys["*User:user"]
+	if user.hits != 1 || user.misses != 3 || user.dedups != 1 {
+		t.Fatalf("user counts = %+v", *user)
+	}
+	if got := user.hitRatio(); got != 0.4 {
+		t.Fatalf("hit ratio = %v, want 0.4", got)
+	}
+	if got := user.saved(); got != 4*time.Millisecond {
+		t.Fatalf("saved = %v, want 4ms", got)
+	}
+	if a.routes["GET /me"].errors != 1 {
+		t.Fatalf("route errors = %d, want 1", a.routes["GET /me"].errors)
+	}
+
+	slow := a.slowest()
+	if len(slow) != 2 || slow[0].Key != "*Org:org" || slow[1].Identifier != "1" {
+		t.Fatalf("slowest = %+v", slow)
+	}
+
+	ps := a.nPlusOnes()
+	if len(ps) != 1 || ps[0].key != "*User:user" || ps[0].route != "GET /feed" || ps[0].maxIDs != 3 {
+		t.Fatalf("n+1 = %+v", ps)
+	}
+
+	if never := a.neverHit(); len(never) != 1 || never[0] != "*Org:org" {
+		t.Fatalf("never hit = %v, want [*Org:org]", never)
+	}
+}
+
+func TestAnalyzerDropsCountedRequests(t *testing.T) {
+	a := newAnalyzer(3, 0)
+	for i := 0; i < 100; i++ {
+		a.add(&callonce.Record{RequestID: "r1", Route: "GET /feed", Event: "miss", Key: "*User:user", Identifier: strconv.Itoa(i)})
+	}
+	if len(a.missed) != 0 {
+		t.Fatalf("kept %d identifier sets after the request was counted", len(a.missed))
+	}
+	ps := a.nPlusOnes()
+	if len(ps) != 1 || ps[0].requests != 1 || ps[0].maxIDs != 100 {
+		t.Fatalf("n+1 = %+v", ps)
+	}
+}
+
+func TestAnalyzerReport(t *testing.T) {
+	a := newAnalyzer(3, 5)
+	if err := a.read(strings.NewReader(sampleLog)); err != nil {
+		t.Fatal(err)
+	}
+	var out strings.Builder
+	a.report(&out)
+
+	for _, want := range []string{"6 records, 4 fetches", "Routes", "Slowest fetches", "N+1 patterns", "Never hit"} {
+		if !strings.Contains(out.String(), want) {
+			t.Fatalf("report missing %q:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestAnalyzerInvalidLine(t *testing.T) {
+	a := newAnalyzer(3, 5)
+	err := a.read(strings.NewReader("{\"event\":\"hit\"}\nnot json\n"))
+	if err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Fatalf("err = %v, want a line 2 error", err)
+	}
+}
+
+func TestAnalyzerSlowestKeepsTop(t *testing.T) {
+	a := newAnalyzer(3, 3)
+	for _, d := range []int{5, 1, 9, 3, 9, 7, 2} {
+		a.add(&callonce.Record{Event: "miss", Key: "k", Identifier: strconv.Itoa(d), Duration: time.Duration(d)})
+	}
+	if len(a.slow) != 3 {
+		t.Fatalf("kept %d fetches, want 3", len(a.slow))
+	}
+
+	var got []time.Duration
+	for _, r := range a.slowest() {
+		got = append(got, r.Duration)
+	}
+	if len(got) != 3 || got[0] != 9 || got[1] != 9 || got[2] != 7 {
+		t.Fatalf("slowest = %v, want [9 9 7]", got)
+	}
+	if a.fetches != 7 {
+		t.Fatalf("fetches = %d, want 7", a.fetches)
+	}
+}
+
+func TestAnalyzerCountsStaleAsServed(t *testing.T) {
+	a := newAnalyzer(3, 5)
+	a.add(&callonce.Record{Event: "miss", Key: "k", Identifier: "1", Duration: time.Millisecond})
+	a.add(&callonce.Record{Event: "stale", Key: "k", Identifier: "1"})
+
+	k := a.keys["k"]
+	if k.stale != 1 || k.hitRatio() != 0.5 {
+		t.Fatalf("got stale=%d hit ratio=%v; want 1, 0.5", k.stale, k.hitRatio())
+	}
+	if never := a.neverHit(); len(never) != 0 {
+		t.Fatalf("never hit = %v, want none", never)
+	}
+}
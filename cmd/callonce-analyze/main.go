@@ -0,0 +1,60 @@
+// Command callonce-analyze summarises callonce event logs written by
+// callonce.JSONLRecorder.
+//
+// Usage:
+//
+//	callonce-analyze [flags] [file ...]
+//
+// With no files it reads standard input. It reports hit ratios per route
+// and per key (counting stale hits as served from cache), time saved by
+// dedups and hits, the slowest fetches, N+1 patterns (one request fetching
+// many distinct identifiers of the same key individually), and keys that
+// are fetched but never hit.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+)
+
+func main() {
+	top := flag.Int("top", 10, "number of slowest fetches to list")
+	nplusone := flag.Int("nplusone", 5, "distinct identifiers missed for one key within one request that count as an N+1 pattern")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: callonce-analyze [flags] [file ...]\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	a := newAnalyzer(*nplusone, *top)
+	if flag.NArg() == 0 {
+		if err := a.read(os.Stdin); err != nil {
+			fatal(err)
+		}
+	}
+	for _, name := range flag.Args() {
+		if err := readFile(a, name); err != nil {
+			fatal(err)
+		}
+	}
+
+	a.report(os.Stdout)
+}
+
+func readFile(a *analyzer, name string) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := a.read(f); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return nil
+}
+
+func fatal(err error) {
+	fmt.Fprintln(os.Stderr, "callonce-analyze:", err)
+	os.Exit(1)
+}
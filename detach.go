@@ -0,0 +1,47 @@
+package callonce
+
+import "context"
+
+// Detach returns a context for background goroutines that outlive the
+// request. Like context.WithoutCancel it is never canceled, and it carries
+// a read-only snapshot of the Cache in ctx, including the values it sees
+// through its parents.
+//
+// Get in the detached context returns values from the snapshot; on a miss
+// it calls fn directly without storing the result, so background work
+// never fetches into a cache that is about to be discarded. Child caches,
+// branches and MergeInto never write into the snapshot either. The
+// snapshot shares the cache's observer and tracer. If ctx has no Cache, Detach is
+// equivalent to context.WithoutCancel.
+func Detach(ctx context.Context) context.Context {
+	detached := context.WithoutCancel(ctx)
+	c := FromContext(ctx)
+	if c == nil {
+		return detached
+	}
+
+	snap := c.fork()
+	snap.store = NewMapStore()
+	snap.parent = nil
+	snap.swr = false
+	snap.readOnly = true
+
+	var chain []*Cache
+	for cc := c; cc != nil; cc = cc.parent {
+		chain = append(chain, cc)
+	}
+	for i := len(chain) - 1; i >= 0; i-- {
+		cc := chain[i]
+		cc.mu.RLock()
+		for key := range cc.hidden {
+			snap.store.Delete(key)
+		}
+		cc.mu.RUnlock()
+		cc.store.Range(func(key StoreKey, stored any) bool {
+			snap.store.Set(key, stored)
+			return true
+		})
+	}
+
+	return context.WithValue(detached, contextKey{}, snap)
+}
@@ -0,0 +1,114 @@
+package callonce_test
+
+import (
+	"context"
+	"testing"
+
+	callonce "github.com/probablyarth/callonce-go"
+)
+
+func TestDetachReadsSnapshot(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	ctx = callonce.WithCache(ctx)
+	callonce.Get(ctx, func() (string, error) { return "request", nil }, callonce.L(testKey, "1"))
+
+	detached := callonce.Detach(ctx)
+	cancel()
+	if detached.Err() != nil {
+		t.Fatalf("detached context canceled: %v", detached.Err())
+	}
+
+	v, _ := callonce.Get(detached, func() (string, error) { return "miss", nil }, callonce.L(testKey, "1"))
+	if v != "request" {
+		t.Fatalf("got %q, want snapshot value %q", v, "request")
+	}
+
+	// Values stored in the request cache after Detach are not in the
+	// snapshot, and misses in the snapshot are not stored anywhere.
+	callonce.Get(ctx, func() (string, error) { return "later", nil }, callonce.L(testKey, "2"))
+	calls := 0
+	for i := 0; i < 2; i++ {
+		callonce.Get(detached, func() (string, error) {
+			calls++
+			return "background", nil
+		}, callonce.L(testKey, "2"))
+	}
+	if calls != 2 {
+		t.Fatalf("fn called %d times, want 2", calls)
+	}
+	if s := callonce.FromContext(ctx).Stats(); s.Entries != 2 {
+		t.Fatalf("request cache has %d entries, want 2", s.Entries)
+	}
+}
+
+func TestDetachFlattensChildCaches(t *testing.T) {
+	parent := callonce.WithCache(context.Background())
+	callonce.Get(parent, func() (string, error) { return "parent", nil }, callonce.L(testKey, "p"))
+	callonce.Get(parent, func() (string, error) { return "parent", nil }, callonce.L(testKey, "hidden"))
+
+	child := callonce.WithChildCache(parent)
+	callonce.Get(child, func() (string, error) { return "child", nil }, callonce.L(testKey, "c"))
+	callonce.Forget(child, callonce.L(testKey, "hidden"))
+
+	detached := callonce.Detach(child)
+	for id, want := range map[string]string{"p": "parent", "c": "child", "hidden": "miss"} {
+		v, _ := callonce.Get(detached, func() (string, error) { return "miss", nil }, callonce.L(testKey, id))
+		if v != want {
+			t.Errorf("%s: got %q, want %q", id, v, want)
+		}
+	}
+}
+
+func TestForkOfDetachedIsReadOnly(t *testing.T) {
+	ctx := callonce.WithCache(context.Background())
+	detached := callonce.Detach(ctx)
+
+	branch, b := callonce.Fork(detached)
+	calls := 0
+	for i := 0; i < 2; i++ {
+		callonce.Get(branch, func() (string, error) {
+			calls++
+			return "branch", nil
+		}, callonce.L(testKey, "1"))
+	}
+	b.Commit()
+	if calls != 2 {
+		t.Fatalf("fn called %d times, want 2", calls)
+	}
+	if s := callonce.FromContext(detached).Stats(); s.Entries != 0 {
+		t.Fatalf("snapshot has %d entries after Commit, want 0", s.Entries)
+	}
+}
+
+func TestDetachedSnapshotIsNotWritten(t *testing.T) {
+	ctx := callonce.WithCache(context.Background())
+	callonce.Get(ctx, func() (string, error) { return "v", nil }, callonce.L(testKey, "1"))
+	detached := callonce.Detach(ctx)
+	snap := callonce.FromContext(detached)
+
+	// A multi-lookup hit doesn't backfill the other lookups.
+	callonce.Get(detached, func() (string, error) { return "miss", nil }, callonce.L(testKey, "1"), callonce.L(testKey, "2"))
+	if s := snap.Stats(); s.Entries != 1 {
+		t.Fatalf("snapshot has %d entries after a hit, want 1", s.Entries)
+	}
+
+	// A child storing at its parent's level stores in itself instead.
+	child := callonce.WithChildCache(detached, callonce.WithStoreLevel(callonce.StoreParent))
+	callonce.Get(child, func() (string, error) { return "child", nil }, callonce.L(testKey, "3"))
+	callonce.FromContext(child).MergeInto(snap)
+	if s := snap.Stats(); s.Entries != 1 {
+		t.Fatalf("snapshot has %d entries after a child miss, want 1", s.Entries)
+	}
+	if s := callonce.FromContext(child).Stats(); s.Entries != 1 {
+		t.Fatalf("child has %d entries, want 1", s.Entries)
+	}
+}
+
+func TestDetachWithoutCache(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	detached := callonce.Detach(ctx)
+	cancel()
+	if detached.Err() != nil || callonce.FromContext(detached) != nil {
+		t.Fatal("want an uncanceled context without a cache")
+	}
+}
@@ -8,8 +8,11 @@
 //
 // Unlike [golang.org/x/sync/singleflight], which deduplicates only during an
 // in-flight call and then forgets the result, callonce persists successful results
-// for the full request lifetime. Unlike a global cache, it requires no eviction
-// policy — the cache is tied to the context and discarded when the request ends.
+// for the full request lifetime. Unlike a global cache, it needs no eviction
+// policy by default — the cache is tied to the context and discarded when the
+// request ends. Contexts that live much longer, such as batch jobs or streaming
+// RPCs, can opt in to LRU eviction with [WithMaxEntries] and [WithMaxBytes], and
+// to expiry with [WithTTL].
 //
 // # Behavior
 //
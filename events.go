@@ -1,5 +1,10 @@
 package callonce
 
+import (
+	"context"
+	"time"
+)
+
 // Observer receives cache lifecycle events. Implementations must be safe
 // for concurrent use when the cache is accessed from multiple goroutines.
 type Observer interface {
@@ -12,16 +17,75 @@ type Event int
 const (
 	// EventHit is emitted when a Get call finds a cached value.
 	EventHit Event = iota
-	// EventMiss is emitted when a Get call invokes fn.
+	// EventMiss is emitted when a Get call invokes fn. It is delivered
+	// after fn returns and carries fn's duration and error.
 	EventMiss
 	// EventDedup is emitted when a concurrent caller shares an in-flight
-	// singleflight result instead of triggering a new call.
+	// result instead of triggering a new call.
 	EventDedup
+	// EventForget is emitted for each lookup passed to Forget.
+	EventForget
+	// EventNPlusOne is emitted when more than the configured threshold of
+	// distinct identifiers of one key were fetched individually. See
+	// WithNPlusOneDetector.
+	EventNPlusOne
+	// EventEvict is emitted when a value is evicted to keep the cache
+	// within the limits set by WithMaxEntries or WithMaxBytes.
+	EventEvict
+	// EventStale is emitted when Get returns an expired value and
+	// refreshes it in the background. See WithStaleWhileRevalidate.
+	EventStale
+	// EventRefreshError is emitted when a background refresh fails. Err
+	// holds fn's error, or the recovered panic.
+	EventRefreshError
+	// EventPromote is emitted by a parent cache for each value copied
+	// into it by MergeInto or Promote.
+	EventPromote
 )
 
+// String returns the lower-case name of the event, e.g. "hit".
+func (e Event) String() string {
+	switch e {
+	case EventHit:
+		return "hit"
+	case EventMiss:
+		return "miss"
+	case EventDedup:
+		return "dedup"
+	case EventForget:
+		return "forget"
+	case EventNPlusOne:
+		return "nplusone"
+	case EventEvict:
+		return "evict"
+	case EventStale:
+		return "stale"
+	case EventRefreshError:
+		return "refresh_error"
+	case EventPromote:
+		return "promote"
+	}
+	return "unknown"
+}
+
 // EventData carries the details of a cache event.
 type EventData struct {
 	Event      Event
 	Key        string
 	Identifier string
+	// Time is when the event happened. For EventMiss it is when fn
+	// returned, so fn started at Time.Add(-Duration).
+	Time time.Time
+	// Duration is how long fn ran for EventMiss, and how long the caller
+	// waited for the in-flight call for EventDedup.
+	Duration time.Duration
+	// Err is the error returned by fn. Only set for EventMiss and
+	// EventRefreshError.
+	Err error
+	// Count is the number of distinct identifiers fetched for Key. Only
+	// set for EventNPlusOne.
+	Count int
+	// Context is the context passed to the Get, Forget or Promote call
+	// that produced the event. It is nil for evictions.
+	Context context.Context
 }
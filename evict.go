@@ -0,0 +1,170 @@
+package callonce
+
+import (
+	"container/list"
+	"sync"
+)
+
+// WithMaxEntries bounds the number of values the cache holds. When a write
+// takes the cache over n entries, the least recently used ones are evicted
+// and EventEvict is emitted for each. Values below 1 are ignored.
+func WithMaxEntries(n int) Option {
+	return func(cache *Cache) {
+		if n > 0 {
+			cache.maxEntries = n
+		}
+	}
+}
+
+// WithMaxBytes bounds the total size of the values the cache holds, as
+// reported by sizer, evicting the least recently used ones like
+// WithMaxEntries. A value stored under several lookups is counted once per
+// lookup. Values of n below 1 and a nil sizer are ignored.
+func WithMaxBytes(n int, sizer func(value any) int) Option {
+	return func(cache *Cache) {
+		if n > 0 && sizer != nil {
+			cache.maxBytes = n
+			cache.sizer = sizer
+		}
+	}
+}
+
+// bound wraps s in an LRU store when a size limit is configured.
+func (c *Cache) bound(s Store) Store {
+	if c.maxEntries == 0 && c.maxBytes == 0 {
+		return s
+	}
+	return &boundedStore{
+		next:       s,
+		cache:      c,
+		maxEntries: c.maxEntries,
+		maxBytes:   c.maxBytes,
+		sizer:      c.sizer,
+		items:      make(map[StoreKey]*list.Element),
+	}
+}
+
+// inFlight reports whether fn is currently running for key.
+func (c *Cache) inFlight(key StoreKey) bool {
+	fl := c.flightFor(key)
+	fl.mu.Lock()
+	_, ok := fl.calls[key]
+	fl.mu.Unlock()
+	return ok
+}
+
+// evicted emits EventEvict for each key and drops its span.
+func (c *Cache) evicted(keys []StoreKey) {
+	if c.tracer != nil {
+		c.mu.Lock()
+		for _, k := range keys {
+			delete(c.spans, k)
+		}
+		c.mu.Unlock()
+	}
+	for _, k := range keys {
+		c.emit(EventData{Event: EventEvict, Key: k.Key, Identifier: k.Identifier})
+	}
+}
+
+// boundedStore tracks the recency of the keys in next and evicts the least
+// recently used ones when a limit is exceeded. Keys whose fn is still
+// running are skipped, so a limit may be exceeded until the next write.
+//
+// A single mutex guards the recency list, so a bounded cache does not get
+// the lock sharding of the default store.
+type boundedStore struct {
+	next       Store
+	cache      *Cache
+	maxEntries int
+	maxBytes   int
+	sizer      func(any) int
+
+	mu    sync.Mutex
+	lru   list.List // of *lruEntry, most recently used first
+	items map[StoreKey]*list.Element
+	bytes int
+}
+
+type lruEntry struct {
+	key  StoreKey
+	size int
+}
+
+func (s *boundedStore) Get(key StoreKey) (any, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v, ok := s.next.Get(key)
+	if el, tracked := s.items[key]; ok && tracked {
+		s.lru.MoveToFront(el)
+	}
+	return v, ok
+}
+
+func (s *boundedStore) Set(key StoreKey, value any) {
+	size := 0
+	if s.sizer != nil {
+		size = s.sizer(storedValue(value))
+	}
+
+	s.mu.Lock()
+	s.next.Set(key, value)
+	if el, ok := s.items[key]; ok {
+		e := el.Value.(*lruEntry)
+		s.bytes += size - e.size
+		e.size = size
+		s.lru.MoveToFront(el)
+	} else {
+		s.items[key] = s.lru.PushFront(&lruEntry{key: key, size: size})
+		s.bytes += size
+	}
+	evicted := s.evict()
+	s.mu.Unlock()
+
+	if len(evicted) > 0 {
+		s.cache.evicted(evicted)
+	}
+}
+
+func (s *boundedStore) Delete(key StoreKey) {
+	s.mu.Lock()
+	s.next.Delete(key)
+	if el, ok := s.items[key]; ok {
+		s.remove(el)
+	}
+	s.mu.Unlock()
+}
+
+func (s *boundedStore) Range(fn func(key StoreKey, value any) bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.next.Range(fn)
+}
+
+// evict removes least recently used entries until the store is within its
+// limits or only in-flight keys are left. It returns the evicted keys.
+func (s *boundedStore) evict() []StoreKey {
+	var evicted []StoreKey
+	for el := s.lru.Back(); el != nil && s.over(); {
+		prev := el.Prev()
+		key := el.Value.(*lruEntry).key
+		if !s.cache.inFlight(key) {
+			s.next.Delete(key)
+			s.remove(el)
+			evicted = append(evicted, key)
+		}
+		el = prev
+	}
+	return evicted
+}
+
+func (s *boundedStore) over() bool {
+	return (s.maxEntries > 0 && s.lru.Len() > s.maxEntries) ||
+		(s.maxBytes > 0 && s.bytes > s.maxBytes)
+}
+
+func (s *boundedStore) remove(el *list.Element) {
+	s.bytes -= el.Value.(*lruEntry).size
+	delete(s.items, el.Value.(*lruEntry).key)
+	s.lru.Remove(el)
+}
@@ -0,0 +1,87 @@
+package callonce_test
+
+import (
+	"context"
+	"testing"
+
+	callonce "github.com/probablyarth/callonce-go"
+)
+
+func TestMaxEntriesEvictsLeastRecentlyUsed(t *testing.T) {
+	rec := &recordingObserver{}
+	ctx := callonce.WithCache(context.Background(),
+		callonce.WithMaxEntries(2),
+		callonce.WithObserver(rec),
+	)
+	key := callonce.NewKey[string]("item")
+
+	calls := map[string]int{}
+	get := func(id string) {
+		callonce.Get(ctx, func() (string, error) {
+			calls[id]++
+			return id, nil
+		}, callonce.L(key, id))
+	}
+
+	get("a")
+	get("b")
+	get("a") // a is now more recently used than b
+	get("c") // evicts b
+	get("a")
+	get("b")
+
+	if calls["a"] != 1 || calls["b"] != 2 || calls["c"] != 1 {
+		t.Fatalf("got calls %v, want a=1 b=2 c=1", calls)
+	}
+
+	var evicted []string
+	for _, e := range rec.snapshot() {
+		if e.Event == callonce.EventEvict {
+			evicted = append(evicted, e.Identifier)
+		}
+	}
+	// Refetching b evicts c, the least recently used after a's last hit.
+	if len(evicted) != 2 || evicted[0] != "b" || evicted[1] != "c" {
+		t.Fatalf("evicted %v, want [b c]", evicted)
+	}
+
+	s := callonce.FromContext(ctx).Stats()
+	if s.Entries != 2 || s.Evictions != 2 {
+		t.Fatalf("got entries=%d evictions=%d; want 2, 2", s.Entries, s.Evictions)
+	}
+}
+
+func TestMaxBytesEvictsBySize(t *testing.T) {
+	ctx := callonce.WithCache(context.Background(),
+		callonce.WithMaxBytes(10, func(v any) int { return len(v.(string)) }),
+	)
+	key := callonce.NewKey[string]("blob")
+
+	callonce.Get(ctx, func() (string, error) { return "1234", nil }, callonce.L(key, "a"))
+	callonce.Get(ctx, func() (string, error) { return "1234", nil }, callonce.L(key, "b"))
+	callonce.Get(ctx, func() (string, error) { return "123456", nil }, callonce.L(key, "c"))
+
+	s := callonce.FromContext(ctx).Stats()
+	if s.Entries != 2 || s.Evictions != 1 {
+		t.Fatalf("got entries=%d evictions=%d; want 2, 1", s.Entries, s.Evictions)
+	}
+}
+
+func TestMaxEntriesSkipsInFlightKeys(t *testing.T) {
+	ctx := callonce.WithCache(context.Background(), callonce.WithMaxEntries(1))
+	key := callonce.NewKey[string]("item")
+
+	// The result is stored under a and then b while the call for a is
+	// still in flight, so b is evicted instead of a.
+	callonce.Get(ctx, func() (string, error) { return "v", nil },
+		callonce.L(key, "a"), callonce.L(key, "b"))
+
+	var called bool
+	callonce.Get(ctx, func() (string, error) {
+		called = true
+		return "v", nil
+	}, callonce.L(key, "a"))
+	if called {
+		t.Fatal("in-flight key a was evicted")
+	}
+}
@@ -3,7 +3,9 @@ package main
 import (
 	"fmt"
 	"log"
+	"log/slog"
 	"net/http"
+	"os"
 	"sync/atomic"
 
 	"github.com/probablyarth/callonce-go"
@@ -15,6 +17,16 @@ var fetchCount atomic.Int32
 
 var userKey = callonce.NewKey[string]("user")
 
+// observer logs every cache event as a structured line.
+var observer = callonce.SlogObserver(
+	slog.New(slog.NewTextHandler(os.Stderr, nil)),
+	&callonce.SlogOptions{Levels: map[callonce.Event]slog.Leveler{
+		callonce.EventHit:   slog.LevelInfo,
+		callonce.EventMiss:  slog.LevelInfo,
+		callonce.EventDedup: slog.LevelInfo,
+	}},
+)
+
 func fetchUser(id string) func() (string, error) {
 	return func() (string, error) {
 		n := fetchCount.Add(1)
@@ -23,27 +35,13 @@ func fetchUser(id string) func() (string, error) {
 	}
 }
 
-// logger implements callonce.Observer and logs every cache event.
-type logger struct{}
-
-func (l *logger) On(e callonce.EventData) {
-	switch e.Event {
-	case callonce.EventHit:
-		log.Printf("[callonce] HIT   key=%s id=%s", e.Key, e.Identifier)
-	case callonce.EventMiss:
-		log.Printf("[callonce] MISS  key=%s id=%s", e.Key, e.Identifier)
-	case callonce.EventDedup:
-		log.Printf("[callonce] DEDUP key=%s id=%s", e.Key, e.Identifier)
-	}
-}
-
 func main() {
 	e := echo.New()
 
 	// Middleware: attach a callonce cache with observer to every request.
 	e.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
-			ctx := callonce.WithCache(c.Request().Context(), callonce.WithObserver(&logger{}))
+			ctx := callonce.WithCache(c.Request().Context(), callonce.WithObserver(observer))
 			c.SetRequest(c.Request().WithContext(ctx))
 			return next(c)
 		}
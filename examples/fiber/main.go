@@ -3,6 +3,8 @@ package main
 import (
 	"fmt"
 	"log"
+	"log/slog"
+	"os"
 	"sync/atomic"
 
 	"github.com/probablyarth/callonce-go"
@@ -14,6 +16,16 @@ var fetchCount atomic.Int32
 
 var userKey = callonce.NewKey[string]("user")
 
+// observer logs every cache event as a structured line.
+var observer = callonce.SlogObserver(
+	slog.New(slog.NewTextHandler(os.Stderr, nil)),
+	&callonce.SlogOptions{Levels: map[callonce.Event]slog.Leveler{
+		callonce.EventHit:   slog.LevelInfo,
+		callonce.EventMiss:  slog.LevelInfo,
+		callonce.EventDedup: slog.LevelInfo,
+	}},
+)
+
 func fetchUser(id string) func() (string, error) {
 	return func() (string, error) {
 		n := fetchCount.Add(1)
@@ -22,26 +34,12 @@ func fetchUser(id string) func() (string, error) {
 	}
 }
 
-// logger implements callonce.Observer and logs every cache event.
-type logger struct{}
-
-func (l *logger) On(e callonce.EventData) {
-	switch e.Event {
-	case callonce.EventHit:
-		log.Printf("[callonce] HIT   key=%s id=%s", e.Key, e.Identifier)
-	case callonce.EventMiss:
-		log.Printf("[callonce] MISS  key=%s id=%s", e.Key, e.Identifier)
-	case callonce.EventDedup:
-		log.Printf("[callonce] DEDUP key=%s id=%s", e.Key, e.Identifier)
-	}
-}
-
 func main() {
 	app := fiber.New()
 
 	// Middleware: attach a callonce cache with observer to every request.
 	app.Use(func(c *fiber.Ctx) error {
-		ctx := callonce.WithCache(c.UserContext(), callonce.WithObserver(&logger{}))
+		ctx := callonce.WithCache(c.UserContext(), callonce.WithObserver(observer))
 		c.SetUserContext(ctx)
 		return c.Next()
 	})
@@ -0,0 +1,128 @@
+package callonce
+
+import (
+	"encoding/json"
+	"expvar"
+	"math"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+)
+
+// DefaultBuckets are the histogram upper bounds, in seconds, used by
+// NewExpvarSink. Each sink copies them when it is created.
+var DefaultBuckets = []float64{.001, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// ExpvarSink is a MetricsSink that publishes its metrics with the expvar
+// package, so they appear under /debug/vars.
+type ExpvarSink struct {
+	root    *expvar.Map
+	buckets []float64
+
+	// mu serializes creating metrics and histograms; reads go through the
+	// expvar maps, which are safe for concurrent use.
+	mu sync.Mutex
+}
+
+// NewExpvarSink publishes an expvar.Map named name and returns a sink that
+// writes into it. The map holds one entry per metric, each keyed by the
+// cache key name. Like expvar.Publish, it panics if name is already in use.
+func NewExpvarSink(name string) *ExpvarSink {
+	return &ExpvarSink{
+		root:    expvar.NewMap(name),
+		buckets: append([]float64(nil), DefaultBuckets...),
+	}
+}
+
+// ExpvarObserver returns a MetricsObserver backed by NewExpvarSink(name).
+func ExpvarObserver(name string) Observer {
+	return MetricsObserver(NewExpvarSink(name))
+}
+
+// IncCounter implements MetricsSink.
+func (s *ExpvarSink) IncCounter(name, key string) {
+	s.metric(name).Add(key, 1)
+}
+
+// ObserveHistogram implements MetricsSink.
+func (s *ExpvarSink) ObserveHistogram(name, key string, value float64) {
+	m := s.metric(name)
+	h, ok := m.Get(key).(*histogram)
+	if !ok {
+		s.mu.Lock()
+		if h, ok = m.Get(key).(*histogram); !ok {
+			h = newHistogram(s.buckets)
+			m.Set(key, h)
+		}
+		s.mu.Unlock()
+	}
+	h.observe(value)
+}
+
+func (s *ExpvarSink) metric(name string) *expvar.Map {
+	if m, ok := s.root.Get(name).(*expvar.Map); ok {
+		return m
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	m, ok := s.root.Get(name).(*expvar.Map)
+	if !ok {
+		m = new(expvar.Map).Init()
+		s.root.Set(name, m)
+	}
+	return m
+}
+
+// histogram is an expvar.Var with fixed buckets. It renders as
+// {"count":n,"sum":s,"buckets":{"0.005":n,...,"+Inf":n}} where bucket
+// counts are cumulative, as in Prometheus.
+type histogram struct {
+	bounds []float64
+	counts []atomic.Uint64 // len(bounds)+1; the last bucket is +Inf
+	count  atomic.Uint64
+	sum    atomic.Uint64 // float64 bits
+}
+
+func newHistogram(bounds []float64) *histogram {
+	return &histogram{
+		bounds: bounds,
+		counts: make([]atomic.Uint64, len(bounds)+1),
+	}
+}
+
+func (h *histogram) observe(v float64) {
+	i := sort.SearchFloat64s(h.bounds, v)
+	h.counts[i].Add(1)
+	h.count.Add(1)
+	for {
+		old := h.sum.Load()
+		if h.sum.CompareAndSwap(old, math.Float64bits(math.Float64frombits(old)+v)) {
+			return
+		}
+	}
+}
+
+// String implements expvar.Var.
+func (h *histogram) String() string {
+	var cum uint64
+	buckets := make(map[string]uint64, len(h.counts))
+	for i := range h.counts {
+		cum += h.counts[i].Load()
+		le := "+Inf"
+		if i < len(h.bounds) {
+			le = strconv.FormatFloat(h.bounds[i], 'g', -1, 64)
+		}
+		buckets[le] = cum
+	}
+	b, _ := json.Marshal(struct {
+		Count   uint64            `json:"count"`
+		Sum     float64           `json:"sum"`
+		Buckets map[string]uint64 `json:"buckets"`
+	}{
+		Count:   h.count.Load(),
+		Sum:     math.Float64frombits(h.sum.Load()),
+		Buckets: buckets,
+	})
+	return string(b)
+}
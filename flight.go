@@ -0,0 +1,235 @@
+package callonce
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// errGoexit is handed to dedup waiters when fn calls runtime.Goexit.
+var errGoexit = errors.New("callonce: fn called runtime.Goexit")
+
+// panicError wraps a value recovered from fn so that it can be re-raised in
+// the leader and every dedup waiter.
+type panicError struct {
+	value any
+	stack []byte
+}
+
+func (p *panicError) Error() string {
+	return fmt.Sprintf("%v\n\n%s", p.value, p.stack)
+}
+
+// call is an in-flight fn execution that dedup waiters can join. Calls are
+// pooled: the leader and every waiter hold a reference, and the last one to
+// release it returns it to the pool.
+type call struct {
+	wg    sync.WaitGroup
+	refs  atomic.Int32
+	val   any
+	err   error
+	panic *panicError
+	span  Span
+}
+
+var callPool = sync.Pool{New: func() any { return new(call) }}
+
+func (cl *call) release() {
+	if cl.refs.Add(-1) != 0 {
+		return
+	}
+	cl.val, cl.err, cl.panic, cl.span = nil, nil, nil, nil
+	callPool.Put(cl)
+}
+
+// flightShard holds the in-flight calls for a subset of keys. A cache has
+// one unless WithShards spreads its calls over several.
+type flightShard struct {
+	mu    sync.Mutex
+	calls map[StoreKey]*call
+}
+
+// load runs fn for lookups, or joins a call already running for
+// lookups[0]. It stores a successful result under every lookup.
+//
+// Calls are tracked by the cache the result is stored in, so child caches
+// that share a store level also share in-flight calls.
+func load[T any](ctx context.Context, c *Cache, fn func() (T, error), lookups []Lookup[T]) (T, error) {
+	key := lookups[0].storeKey()
+	fl := c.target().flightFor(key)
+
+	cl, leader := fl.join(key)
+	if !leader {
+		joined := time.Now()
+		cl.wg.Wait()
+		return wait(ctx, c, cl, lookups[0], joined)
+	}
+
+	finished := false
+	defer func() {
+		if !finished {
+			// fn called runtime.Goexit; unblock the waiters.
+			cl.err = errGoexit
+			fl.finish(key, cl)
+			cl.release()
+		}
+	}()
+	lead(ctx, c, cl, fn, lookups)
+	finished = true
+	fl.finish(key, cl)
+
+	val, err, p := cl.val, cl.err, cl.panic
+	cl.release()
+	if p != nil {
+		panic(p)
+	}
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	return val.(T), nil
+}
+
+// join returns the call in flight for key with a reference held, or
+// publishes a new call and reports that the caller leads it.
+func (fl *flightShard) join(key StoreKey) (cl *call, leader bool) {
+	fl.mu.Lock()
+	defer fl.mu.Unlock()
+	if cl, ok := fl.calls[key]; ok {
+		cl.refs.Add(1)
+		return cl, false
+	}
+	cl = callPool.Get().(*call)
+	cl.refs.Store(1)
+	cl.wg.Add(1)
+	if fl.calls == nil {
+		fl.calls = make(map[StoreKey]*call)
+	}
+	fl.calls[key] = cl
+	return cl, true
+}
+
+// finish removes cl from the shard and wakes its waiters.
+func (fl *flightShard) finish(key StoreKey, cl *call) {
+	fl.mu.Lock()
+	delete(fl.calls, key)
+	fl.mu.Unlock()
+	cl.wg.Done()
+}
+
+// wait collects the result of a call that a dedup waiter joined at joined.
+func wait[T any](ctx context.Context, c *Cache, cl *call, lookup Lookup[T], joined time.Time) (T, error) {
+	val, err, p, span := cl.val, cl.err, cl.panic, cl.span
+	cl.release()
+	if p != nil {
+		panic(p)
+	}
+
+	c.emit(EventData{
+		Event:      EventDedup,
+		Key:        lookup.Key.name,
+		Identifier: lookup.Identifier,
+		Duration:   time.Since(joined),
+		Context:    ctx,
+	})
+	c.link(ctx, EventDedup, lookup.Key.name, lookup.Identifier, span)
+
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	return val.(T), nil
+}
+
+// lead runs fn on behalf of cl's waiters and records the outcome in cl. A
+// panic in fn is recovered into cl.panic.
+func lead[T any](ctx context.Context, c *Cache, cl *call, fn func() (T, error), lookups []Lookup[T]) {
+	spanEnded := false
+	defer func() {
+		if r := recover(); r != nil {
+			cl.panic = &panicError{value: r, stack: debug.Stack()}
+			if cl.span != nil && !spanEnded {
+				cl.span.End(cl.panic)
+			}
+		}
+	}()
+
+	// Double-check: another goroutine may have cached while we waited.
+	if v, ok := hit(ctx, c, lookups); ok {
+		cl.val = v
+		return
+	}
+
+	name, identifier := lookups[0].Key.name, lookups[0].Identifier
+	release, err := c.acquireFetch(ctx, name)
+	if err != nil {
+		cl.err = err
+		return
+	}
+	defer release()
+
+	if err := c.reserveMiss(name); err != nil {
+		cl.err = err
+		return
+	}
+
+	cl.span = c.startSpan(ctx, lookups[0].storeKey())
+	start := time.Now()
+	result, err := callFn(ctx, c, name, fn)
+	duration := time.Since(start)
+	if cl.span != nil {
+		cl.span.End(err)
+		spanEnded = true
+	}
+	c.emit(EventData{
+		Event:      EventMiss,
+		Key:        name,
+		Identifier: identifier,
+		Duration:   duration,
+		Err:        err,
+		Context:    ctx,
+	})
+	c.observeMiss(ctx, name, identifier)
+	if err != nil {
+		cl.err = err
+		return
+	}
+
+	// Store under ALL keys.
+	cl.val = result
+	for _, l := range lookups {
+		c.put(l.storeKey(), c.target().expire(l.Key.name, cl.val))
+		c.setSpan(l.storeKey(), cl.span)
+	}
+}
+
+// hit returns the first unexpired cached value among lookups. On a hit it
+// backfills the value, with its original expiry, under the other lookups
+// unless c is a read-only snapshot.
+func hit[T any](ctx context.Context, c *Cache, lookups []Lookup[T]) (any, bool) {
+	for _, lookup := range lookups {
+		stored, owner, ok := c.lookup(lookup.storeKey())
+		if !ok {
+			continue
+		}
+		v, ok := owner.unexpired(stored)
+		if !ok {
+			continue
+		}
+		span := c.span(lookup.storeKey())
+		c.emit(EventData{Event: EventHit, Key: lookup.Key.name, Identifier: lookup.Identifier, Context: ctx})
+		c.link(ctx, EventHit, lookup.Key.name, lookup.Identifier, span)
+		if len(lookups) > 1 && !c.readOnly {
+			for _, l2 := range lookups {
+				c.put(l2.storeKey(), stored)
+				c.setSpan(l2.storeKey(), span)
+			}
+		}
+		return v, true
+	}
+	return nil, false
+}
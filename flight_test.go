@@ -0,0 +1,139 @@
+package callonce_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	callonce "github.com/probablyarth/callonce-go"
+)
+
+func TestPanicPropagatesToWaiters(t *testing.T) {
+	ctx := callonce.WithCache(context.Background())
+
+	const n = 5
+	release := make(chan struct{})
+	started := make(chan struct{})
+	// A goroutine that arrives after the leader panicked leads a new call,
+	// which must panic the same way.
+	var startOnce sync.Once
+	panics := make(chan any, n)
+	var wg sync.WaitGroup
+	wg.Add(n)
+	for i := 0; i < n; i++ {
+		go func() {
+			defer wg.Done()
+			defer func() { panics <- recover() }()
+			callonce.Get(ctx, func() (string, error) {
+				startOnce.Do(func() { close(started) })
+				<-release
+				panic("kaboom")
+			}, callonce.L(testKey, "1"))
+		}()
+	}
+	<-started
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	close(panics)
+
+	for r := range panics {
+		if r == nil || !strings.Contains(fmt.Sprint(r), "kaboom") {
+			t.Fatalf("got panic %v, want it to contain kaboom", r)
+		}
+	}
+}
+
+func TestGoexitUnblocksWaiters(t *testing.T) {
+	ctx := callonce.WithCache(context.Background())
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	go callonce.Get(ctx, func() (string, error) {
+		close(started)
+		<-release
+		runtime.Goexit()
+		return "", nil
+	}, callonce.L(testKey, "1"))
+	<-started
+
+	done := make(chan error)
+	go func() {
+		_, err := callonce.Get(ctx, func() (string, error) { return "v", nil }, callonce.L(testKey, "1"))
+		done <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+
+	select {
+	case err := <-done:
+		// The waiter either joined the aborted call or ran fn itself.
+		if err != nil && !strings.Contains(err.Error(), "Goexit") {
+			t.Fatalf("unexpected error %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("waiter blocked after the leader called runtime.Goexit")
+	}
+
+	v, err := callonce.Get(ctx, func() (string, error) { return "v", nil }, callonce.L(testKey, "1"))
+	if err != nil || v != "v" {
+		t.Fatalf("got %q, %v; want v, nil", v, err)
+	}
+}
+
+func TestCallsReusedAcrossKeys(t *testing.T) {
+	// Exercise the call pool under the race detector.
+	ctx := callonce.WithCache(context.Background())
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(2)
+		id := fmt.Sprint(i % 5)
+		for j := 0; j < 2; j++ {
+			go func() {
+				defer wg.Done()
+				v, err := callonce.Get(ctx, func() (string, error) { return id, nil }, callonce.L(testKey, id))
+				if err != nil || v != id {
+					t.Errorf("got %q, %v; want %q", v, err, id)
+				}
+				callonce.Forget(ctx, callonce.L(testKey, id))
+			}()
+		}
+	}
+	wg.Wait()
+}
+
+// The miss and error paths must not allocate beyond boxing the result, even
+// when fn is a closure capturing local variables.
+var errAllocs = errors.New("fail")
+
+func TestMissAndErrorPathAllocs(t *testing.T) {
+	ctx := callonce.WithCache(context.Background())
+	key := callonce.NewKey[struct{}]("allocs")
+	lookup := callonce.L(key, "1")
+
+	// fn escapes to the heap for stale-while-revalidate refreshes, so a
+	// capturing closure would allocate. This one doesn't.
+	miss := testing.AllocsPerRun(100, func() {
+		callonce.Get(ctx, func() (struct{}, error) {
+			return struct{}{}, nil
+		}, lookup)
+		callonce.Forget(ctx, lookup)
+	})
+	if miss != 0 {
+		t.Errorf("miss allocated %v times per run, want 0", miss)
+	}
+
+	failed := testing.AllocsPerRun(100, func() {
+		callonce.Get(ctx, func() (struct{}, error) {
+			return struct{}{}, errAllocs
+		}, lookup)
+	})
+	if failed != 0 {
+		t.Errorf("failed call allocated %v times per run, want 0", failed)
+	}
+}
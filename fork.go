@@ -0,0 +1,180 @@
+package callonce
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Branch is a copy-on-write view of a Cache created by Fork. Values
+// fetched and forgotten through the branch's context stay in the branch
+// until Commit applies them to the base cache or Discard drops them.
+type Branch struct {
+	base  *Cache
+	cache *Cache
+	store *branchStore
+	mu    sync.Mutex
+	ended bool
+}
+
+// Fork returns a child context whose Cache is a copy-on-write view of the
+// Cache in ctx, for speculative work such as A/B rendering or optimistic
+// mutations. Get in the returned context sees the base cache's values, but
+// stores new values in the branch, and Forget hides base values without
+// touching them.
+//
+// The branch shares the base cache's observer, tracer, expiry, miss budget,
+// fetch limits and N+1 detector. A branch of a read-only cache, such as one
+// returned by Detach, is read-only too. If ctx has no Cache, Fork returns
+// ctx and a Branch whose Commit and Discard do nothing.
+func Fork(ctx context.Context) (context.Context, *Branch) {
+	base := FromContext(ctx)
+	if base == nil {
+		return ctx, &Branch{}
+	}
+	store := &branchStore{s: NewMapStore()}
+	f := base.fork()
+	f.store = store
+	b := &Branch{base: base, cache: f, store: store}
+	return context.WithValue(ctx, contextKey{}, b.cache), b
+}
+
+// fork returns a child of c that shares c's configuration. The caller sets
+// its store.
+func (c *Cache) fork() *Cache {
+	f := &Cache{
+		observer: c.observer,
+		tracer:   c.tracer,
+		profile:  c.profile,
+		name:     c.name,
+		nplusone: c.nplusone,
+		budget:   c.budget,
+		limits:   c.limits,
+		created:  time.Now(),
+		ttl:      c.ttl,
+		keyTTL:   c.keyTTL,
+		clock:    c.clock,
+		swr:      c.swr,
+		maxStale: c.maxStale,
+		parent:   c,
+		readOnly: c.readOnly,
+	}
+	f.stats.perKey = c.stats.perKey
+	if f.tracer != nil {
+		f.spans = make(map[StoreKey]Span)
+	}
+	return f
+}
+
+// Commit applies the branch to the base cache: values forgotten in the
+// branch are deleted from the base with EventForget, and values fetched in
+// the branch overwrite the base's with EventPromote.
+//
+// Commit and Discard end the branch; only the first call has any effect.
+// After that the branch's context uses the base cache directly: Get stores
+// new values in the base and Forget deletes them from it.
+func (b *Branch) Commit() {
+	if b.base == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.ended {
+		return
+	}
+	b.ended = true
+
+	hidden, entries := b.end()
+	for _, key := range hidden {
+		b.base.store.Delete(key)
+		b.base.hide(key)
+		b.base.forgetSpan(key)
+		b.base.emit(EventData{Event: EventForget, Key: key.Key, Identifier: key.Identifier, Context: context.Background()})
+	}
+	for key, stored := range entries {
+		b.base.store.Set(key, stored)
+		b.base.unhide(key)
+		b.base.setSpan(key, b.cache.span(key))
+		b.base.emit(EventData{Event: EventPromote, Key: key.Key, Identifier: key.Identifier, Context: context.Background()})
+	}
+}
+
+// Discard drops everything fetched and forgotten in the branch and ends
+// it, as described on Commit.
+func (b *Branch) Discard() {
+	if b.base == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.ended {
+		return
+	}
+	b.ended = true
+	b.end()
+}
+
+// end points the branch's store at the base's and returns the branch's
+// hidden keys and stored values. Writes racing with end land either in the
+// returned values or in the base, never in neither.
+func (b *Branch) end() ([]StoreKey, map[StoreKey]any) {
+	old := b.store.swap(b.base.store)
+
+	entries := make(map[StoreKey]any)
+	old.Range(func(key StoreKey, stored any) bool {
+		entries[key] = stored
+		return true
+	})
+
+	c := b.cache
+	c.mu.Lock()
+	hidden := make([]StoreKey, 0, len(c.hidden))
+	for key := range c.hidden {
+		hidden = append(hidden, key)
+	}
+	c.hidden = nil
+	c.mu.Unlock()
+
+	return hidden, entries
+}
+
+// branchStore is a Store whose backing store can be swapped atomically.
+// Each operation holds a read lock for its whole duration, so swap waits
+// for operations on the old store to finish.
+type branchStore struct {
+	mu sync.RWMutex
+	s  Store
+}
+
+func (s *branchStore) Get(key StoreKey) (any, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.s.Get(key)
+}
+
+func (s *branchStore) Set(key StoreKey, value any) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	s.s.Set(key, value)
+}
+
+func (s *branchStore) Delete(key StoreKey) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	s.s.Delete(key)
+}
+
+func (s *branchStore) Range(fn func(key StoreKey, value any) bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	s.s.Range(fn)
+}
+
+// swap replaces the backing store with next and returns the old one.
+func (s *branchStore) swap(next Store) Store {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	old := s.s
+	s.s = next
+	return old
+}
@@ -0,0 +1,120 @@
+package callonce_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	callonce "github.com/probablyarth/callonce-go"
+)
+
+func TestForkDiscard(t *testing.T) {
+	ctx := callonce.WithCache(context.Background())
+	callonce.Get(ctx, func() (string, error) { return "base", nil }, callonce.L(testKey, "kept"))
+
+	branch, b := callonce.Fork(ctx)
+	v, _ := callonce.Get(branch, func() (string, error) { return "miss", nil }, callonce.L(testKey, "kept"))
+	if v != "base" {
+		t.Fatalf("branch got %q, want base value %q", v, "base")
+	}
+	callonce.Get(branch, func() (string, error) { return "speculative", nil }, callonce.L(testKey, "new"))
+	callonce.Forget(branch, callonce.L(testKey, "kept"))
+
+	// Nothing leaks into the base before Commit.
+	if s := callonce.FromContext(ctx).Stats(); s.Entries != 1 {
+		t.Fatalf("base has %d entries, want 1", s.Entries)
+	}
+
+	b.Discard()
+	v, _ = callonce.Get(branch, func() (string, error) { return "miss", nil }, callonce.L(testKey, "kept"))
+	if v != "base" {
+		t.Fatalf("branch got %q after Discard, want %q", v, "base")
+	}
+	v, _ = callonce.Get(ctx, func() (string, error) { return "miss", nil }, callonce.L(testKey, "new"))
+	if v != "miss" {
+		t.Fatalf("base got %q, want discarded value to be gone", v)
+	}
+}
+
+func TestForkCommit(t *testing.T) {
+	rec := &recordingObserver{}
+	ctx := callonce.WithCache(context.Background(), callonce.WithObserver(rec))
+	callonce.Get(ctx, func() (string, error) { return "old", nil }, callonce.L(testKey, "updated"))
+	callonce.Get(ctx, func() (string, error) { return "old", nil }, callonce.L(testKey, "deleted"))
+
+	branch, b := callonce.Fork(ctx)
+	callonce.Forget(branch, callonce.L(testKey, "updated"), callonce.L(testKey, "deleted"))
+	callonce.Get(branch, func() (string, error) { return "new", nil }, callonce.L(testKey, "updated"))
+	b.Commit()
+
+	for id, want := range map[string]string{"updated": "new", "deleted": "refetched"} {
+		v, _ := callonce.Get(ctx, func() (string, error) { return "refetched", nil }, callonce.L(testKey, id))
+		if v != want {
+			t.Errorf("base %s: got %q, want %q", id, v, want)
+		}
+	}
+
+	counts := map[callonce.Event]int{}
+	for _, e := range rec.snapshot() {
+		counts[e.Event]++
+	}
+	// Both Forgets in the branch, plus the committed one in the base.
+	if counts[callonce.EventForget] != 3 || counts[callonce.EventPromote] != 1 {
+		t.Fatalf("got %v, want 3 forgets and 1 promote", counts)
+	}
+}
+
+func TestForkAfterCommit(t *testing.T) {
+	ctx := callonce.WithCache(context.Background())
+	branch, b := callonce.Fork(ctx)
+	b.Commit()
+
+	// An ended branch's context stores into the base.
+	callonce.Get(branch, func() (string, error) { return "late", nil }, callonce.L(testKey, "late"))
+	v, _ := callonce.Get(ctx, func() (string, error) { return "miss", nil }, callonce.L(testKey, "late"))
+	if v != "late" {
+		t.Fatalf("base got %q, want value fetched after Commit", v)
+	}
+
+	b.Discard()
+	v, _ = callonce.Get(ctx, func() (string, error) { return "miss", nil }, callonce.L(testKey, "late"))
+	if v != "late" {
+		t.Fatalf("base got %q after Discard of a committed branch, want %q", v, "late")
+	}
+}
+
+func TestForkCommitConcurrentWrites(t *testing.T) {
+	ctx := callonce.WithCache(context.Background())
+	branch, b := callonce.Fork(ctx)
+
+	const n = 200
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			callonce.Get(branch, func() (string, error) { return fmt.Sprint(i), nil }, callonce.L(testKey, fmt.Sprint(i)))
+		}(i)
+	}
+	b.Commit()
+	wg.Wait()
+
+	// Every write lands in the base, whether it raced ahead of Commit or
+	// came after it.
+	for i := 0; i < n; i++ {
+		v, _ := callonce.Get(ctx, func() (string, error) { return "miss", nil }, callonce.L(testKey, fmt.Sprint(i)))
+		if v != fmt.Sprint(i) {
+			t.Fatalf("key %d: got %q, want %q", i, v, fmt.Sprint(i))
+		}
+	}
+}
+
+func TestForkWithoutCache(t *testing.T) {
+	ctx, b := callonce.Fork(context.Background())
+	if callonce.FromContext(ctx) != nil {
+		t.Fatal("Fork attached a cache to a context without one")
+	}
+	b.Commit()
+	b.Discard()
+}
@@ -0,0 +1,148 @@
+package callonce
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+const defaultJSONLBufferSize = 64 << 10
+
+// Record is one line written by a JSONLRecorder.
+type Record struct {
+	Time       time.Time     `json:"time"`
+	RequestID  string        `json:"request_id,omitempty"`
+	Route      string        `json:"route,omitempty"`
+	Event      string        `json:"event"`
+	Key        string        `json:"key"`
+	Identifier string        `json:"identifier"`
+	Duration   time.Duration `json:"duration_ns,omitempty"`
+	Error      string        `json:"error,omitempty"`
+	Count      int           `json:"count,omitempty"`
+}
+
+// JSONLOptions configures a JSONLRecorder. A nil *JSONLOptions is
+// equivalent to the zero value.
+type JSONLOptions struct {
+	// BufferSize is the size of the write buffer. Defaults to 64 KiB.
+	BufferSize int
+
+	// RequestID, if set, extracts a request ID from the context of the
+	// Get or Forget call.
+	RequestID func(ctx context.Context) string
+
+	// Route, if set, extracts the route or handler name from the context
+	// of the Get or Forget call.
+	Route func(ctx context.Context) string
+}
+
+// JSONLRecorder is an Observer that writes every event as a JSON Lines
+// Record to an io.Writer, for offline analysis.
+//
+// Output is buffered. The underlying writer only ever receives whole
+// lines, so files can be rotated between writes without splitting a
+// record; use Rotate to switch writers. Call Flush before exiting.
+//
+// JSONLRecorder performs I/O inside On. Wrap it in an AsyncObserver to
+// keep it off the Get hot path.
+type JSONLRecorder struct {
+	opts JSONLOptions
+
+	mu  sync.Mutex
+	buf *bufio.Writer
+	err error
+}
+
+// NewJSONLRecorder returns a JSONLRecorder writing to w.
+func NewJSONLRecorder(w io.Writer, opts *JSONLOptions) *JSONLRecorder {
+	r := &JSONLRecorder{}
+	if opts != nil {
+		r.opts = *opts
+	}
+	if r.opts.BufferSize <= 0 {
+		r.opts.BufferSize = defaultJSONLBufferSize
+	}
+	r.buf = bufio.NewWriterSize(w, r.opts.BufferSize)
+	return r
+}
+
+// On implements Observer.
+func (r *JSONLRecorder) On(e EventData) {
+	rec := Record{
+		Time:       e.Time,
+		Event:      e.Event.String(),
+		Key:        e.Key,
+		Identifier: e.Identifier,
+		Duration:   e.Duration,
+		Count:      e.Count,
+	}
+	if e.Err != nil {
+		rec.Error = e.Err.Error()
+	}
+	if e.Context != nil {
+		if r.opts.RequestID != nil {
+			rec.RequestID = r.opts.RequestID(e.Context)
+		}
+		if r.opts.Route != nil {
+			rec.Route = r.opts.Route(e.Context)
+		}
+	}
+
+	line, err := json.Marshal(rec)
+	if err != nil {
+		r.setErr(err)
+		return
+	}
+	line = append(line, '\n')
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil {
+		return
+	}
+	// Flush first rather than letting bufio split the line across writes.
+	if len(line) > r.buf.Available() && r.buf.Buffered() > 0 {
+		if r.err = r.buf.Flush(); r.err != nil {
+			return
+		}
+	}
+	_, r.err = r.buf.Write(line)
+}
+
+// Flush writes buffered records to the underlying writer. It returns the
+// first error encountered by the recorder, if any.
+func (r *JSONLRecorder) Flush() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil {
+		return r.err
+	}
+	r.err = r.buf.Flush()
+	return r.err
+}
+
+// Rotate flushes buffered records to the current writer and directs
+// subsequent records to w. The caller is responsible for closing the old
+// writer. A previous write error is cleared once the switch succeeds.
+func (r *JSONLRecorder) Rotate(w io.Writer) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var err error
+	if r.err == nil {
+		err = r.buf.Flush()
+	}
+	r.buf = bufio.NewWriterSize(w, r.opts.BufferSize)
+	r.err = nil
+	return err
+}
+
+func (r *JSONLRecorder) setErr(err error) {
+	r.mu.Lock()
+	if r.err == nil {
+		r.err = err
+	}
+	r.mu.Unlock()
+}
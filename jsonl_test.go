@@ -0,0 +1,88 @@
+package callonce_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	callonce "github.com/probablyarth/callonce-go"
+)
+
+func readRecords(t *testing.T, s string) []callonce.Record {
+	t.Helper()
+	var out []callonce.Record
+	sc := bufio.NewScanner(strings.NewReader(s))
+	for sc.Scan() {
+		var r callonce.Record
+		if err := json.Unmarshal(sc.Bytes(), &r); err != nil {
+			t.Fatalf("invalid record %q: %v", sc.Text(), err)
+		}
+		out = append(out, r)
+	}
+	return out
+}
+
+func TestJSONLRecorder(t *testing.T) {
+	var buf bytes.Buffer
+	rec := callonce.NewJSONLRecorder(&buf, &callonce.JSONLOptions{
+		RequestID: func(ctx context.Context) string {
+			id, _ := ctx.Value(requestIDKey{}).(string)
+			return id
+		},
+	})
+
+	ctx := context.WithValue(context.Background(), requestIDKey{}, "req-7")
+	ctx = callonce.WithCache(ctx, callonce.WithObserver(rec))
+
+	callonce.Get(ctx, func() (string, error) { return "v", nil }, callonce.L(testKey, "1"))
+	callonce.Get(ctx, func() (string, error) { return "v", nil }, callonce.L(testKey, "1"))
+	callonce.Get(ctx, func() (string, error) { return "", errors.New("boom") }, callonce.L(testKey, "2"))
+
+	if buf.Len() != 0 {
+		t.Fatal("records written before Flush; expected buffering")
+	}
+	if err := rec.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	records := readRecords(t, buf.String())
+	if len(records) != 3 {
+		t.Fatalf("got %d records, want 3", len(records))
+	}
+	if records[0].Event != "miss" || records[1].Event != "hit" || records[2].Error != "boom" {
+		t.Fatalf("unexpected records %+v", records)
+	}
+	for _, r := range records {
+		if r.RequestID != "req-7" || r.Key != "string:test" || r.Time.IsZero() {
+			t.Fatalf("incomplete record %+v", r)
+		}
+	}
+}
+
+func TestJSONLRecorderRotate(t *testing.T) {
+	var first, second bytes.Buffer
+	// A tiny buffer forces flushes; lines must never be split.
+	rec := callonce.NewJSONLRecorder(&first, &callonce.JSONLOptions{BufferSize: 16})
+	rec.On(callonce.EventData{Event: callonce.EventHit, Key: "k", Identifier: "1"})
+	rec.On(callonce.EventData{Event: callonce.EventHit, Key: "k", Identifier: "2"})
+
+	if err := rec.Rotate(&second); err != nil {
+		t.Fatal(err)
+	}
+	rec.On(callonce.EventData{Event: callonce.EventHit, Key: "k", Identifier: "3"})
+	if err := rec.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := len(readRecords(t, first.String())); n != 2 {
+		t.Fatalf("first writer got %d records, want 2", n)
+	}
+	got := readRecords(t, second.String())
+	if len(got) != 1 || got[0].Identifier != "3" {
+		t.Fatalf("second writer got %+v, want record 3", got)
+	}
+}
@@ -26,6 +26,6 @@ func L[T any](key Key[T], identifier string) Lookup[T] {
 	return Lookup[T]{Key: key, Identifier: identifier}
 }
 
-func (l Lookup[T]) getFullKey() string {
-	return l.Key.name + delimiter + l.Identifier
+func (l Lookup[T]) storeKey() StoreKey {
+	return StoreKey{Key: l.Key.name, Identifier: l.Identifier}
 }
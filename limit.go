@@ -0,0 +1,101 @@
+package callonce
+
+import "context"
+
+// WithMaxConcurrentFetches bounds how many fn calls run at the same time
+// within the cache. Further misses wait for a free slot; hits and dedup
+// waiters are never blocked. A miss whose context is done while waiting
+// returns the context's error without calling fn, and dedup waiters on
+// that miss receive the same error. Values below 1 are ignored.
+//
+// fn holds its slot until it returns. A fn that calls Get for another key
+// needs a second slot while holding the first, so once every slot is held
+// by such a fn the nested misses wait until their context is done, or
+// forever if it never is. Leave room for nesting when choosing n, or fetch
+// nested values outside fn.
+func WithMaxConcurrentFetches(n int) Option {
+	return func(cache *Cache) {
+		if n > 0 {
+			cache.ensureLimits().all = make(semaphore, n)
+		}
+	}
+}
+
+// WithKeyMaxConcurrentFetches bounds how many fn calls run at the same time
+// for key, in addition to any cache-wide limit. Values below 1 are ignored.
+// A fn for key that calls Get for the same key name, such as a parent
+// record of the same type, can wait forever in the same way as described
+// on WithMaxConcurrentFetches.
+func WithKeyMaxConcurrentFetches[T any](key Key[T], n int) Option {
+	return func(cache *Cache) {
+		if n < 1 {
+			return
+		}
+		l := cache.ensureLimits()
+		if l.keys == nil {
+			l.keys = make(map[string]semaphore)
+		}
+		l.keys[key.name] = make(semaphore, n)
+	}
+}
+
+type semaphore chan struct{}
+
+func (s semaphore) acquire(ctx context.Context) error {
+	if s == nil {
+		return nil
+	}
+	select {
+	case s <- struct{}{}:
+		return nil
+	default:
+	}
+	select {
+	case s <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s semaphore) release() {
+	if s != nil {
+		<-s
+	}
+}
+
+type fetchLimits struct {
+	all  semaphore
+	keys map[string]semaphore
+}
+
+func (c *Cache) ensureLimits() *fetchLimits {
+	if c.limits == nil {
+		c.limits = &fetchLimits{}
+	}
+	return c.limits
+}
+
+// acquireFetch waits for a fetch slot for keyName. The returned function
+// releases it.
+func (c *Cache) acquireFetch(ctx context.Context, keyName string) (func(), error) {
+	l := c.limits
+	if l == nil {
+		return noop, nil
+	}
+
+	key := l.keys[keyName]
+	if err := key.acquire(ctx); err != nil {
+		return nil, err
+	}
+	if err := l.all.acquire(ctx); err != nil {
+		key.release()
+		return nil, err
+	}
+	return func() {
+		l.all.release()
+		key.release()
+	}, nil
+}
+
+func noop() {}
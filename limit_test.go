@@ -0,0 +1,175 @@
+package callonce_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	callonce "github.com/probablyarth/callonce-go"
+)
+
+func TestMaxConcurrentFetches(t *testing.T) {
+	ctx := callonce.WithCache(context.Background(), callonce.WithMaxConcurrentFetches(2))
+
+	var running, peak atomic.Int32
+	fn := func() (string, error) {
+		n := running.Add(1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		running.Add(-1)
+		return "v", nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if _, err := callonce.Get(ctx, fn, callonce.L(testKey, fmt.Sprint(i))); err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if p := peak.Load(); p != 2 {
+		t.Fatalf("peak concurrency = %d, want 2", p)
+	}
+}
+
+func TestMaxConcurrentFetchesHitsNotBlocked(t *testing.T) {
+	ctx := callonce.WithCache(context.Background(), callonce.WithMaxConcurrentFetches(1))
+	callonce.Get(ctx, func() (string, error) { return "cached", nil }, callonce.L(testKey, "hit"))
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	go callonce.Get(ctx, func() (string, error) {
+		close(started)
+		<-release
+		return "v", nil
+	}, callonce.L(testKey, "slow"))
+	<-started
+	defer close(release)
+
+	done := make(chan string)
+	go func() {
+		v, _ := callonce.Get(ctx, func() (string, error) { return "miss", nil }, callonce.L(testKey, "hit"))
+		done <- v
+	}()
+	select {
+	case v := <-done:
+		if v != "cached" {
+			t.Fatalf("got %q, want cached", v)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("hit blocked behind a running fetch")
+	}
+}
+
+func TestMaxConcurrentFetchesContextCanceled(t *testing.T) {
+	base := callonce.WithCache(context.Background(), callonce.WithMaxConcurrentFetches(1))
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	go callonce.Get(base, func() (string, error) {
+		close(started)
+		<-release
+		return "v", nil
+	}, callonce.L(testKey, "slow"))
+	<-started
+	defer close(release)
+
+	ctx, cancel := context.WithTimeout(base, 10*time.Millisecond)
+	defer cancel()
+	var called bool
+	_, err := callonce.Get(ctx, func() (string, error) {
+		called = true
+		return "v", nil
+	}, callonce.L(testKey, "queued"))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v, want context.DeadlineExceeded", err)
+	}
+	if called {
+		t.Fatal("fn ran after its context expired")
+	}
+}
+
+// A nested miss inside fn can't get a slot while fn holds the only one. It
+// must give up when its context is done instead of hanging.
+func TestMaxConcurrentFetchesNestedMiss(t *testing.T) {
+	ctx := callonce.WithCache(context.Background(), callonce.WithMaxConcurrentFetches(1))
+	nested, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := callonce.Get(ctx, func() (string, error) {
+			return callonce.Get(nested, func() (string, error) { return "org", nil }, callonce.L(testKey, "org"))
+		}, callonce.L(testKey, "user"))
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("got %v, want %v", err, context.DeadlineExceeded)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("nested Get hung waiting for a fetch slot")
+	}
+}
+
+func TestKeyMaxConcurrentFetches(t *testing.T) {
+	slowKey := callonce.NewKey[string]("slow")
+	ctx := callonce.WithCache(context.Background(), callonce.WithKeyMaxConcurrentFetches(slowKey, 1))
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	go callonce.Get(ctx, func() (string, error) {
+		close(started)
+		<-release
+		return "v", nil
+	}, callonce.L(slowKey, "1"))
+	<-started
+	defer close(release)
+
+	// A different key is not limited.
+	done := make(chan struct{})
+	go func() {
+		callonce.Get(ctx, func() (string, error) { return "v", nil }, callonce.L(testKey, "1"))
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("unrelated key blocked by per-key limit")
+	}
+}
+
+func TestMaxConcurrentFetchesIgnoresBelowOne(t *testing.T) {
+	for _, n := range []int{0, -1} {
+		ctx := callonce.WithCache(context.Background(),
+			callonce.WithMaxConcurrentFetches(n),
+			callonce.WithKeyMaxConcurrentFetches(testKey, n),
+		)
+		done := make(chan struct{})
+		go func() {
+			callonce.Get(ctx, func() (string, error) { return "v", nil }, callonce.L(testKey, "1"))
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatalf("n=%d: miss blocked", n)
+		}
+	}
+}
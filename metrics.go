@@ -0,0 +1,67 @@
+package callonce
+
+// Metric names reported to a MetricsSink.
+const (
+	MetricHits          = "hits"
+	MetricMisses        = "misses"
+	MetricDedups        = "dedups"
+	MetricErrors        = "errors"
+	MetricForgets       = "forgets"
+	MetricNPlusOne      = "nplusone"
+	MetricEvictions     = "evictions"
+	MetricStaleHits     = "stale_hits"
+	MetricRefreshErrors = "refresh_errors"
+	MetricPromotions    = "promotions"
+	MetricFnDuration    = "fn_duration_seconds"
+)
+
+// MetricsSink is a minimal counter and histogram interface that metrics
+// backends implement. Every metric is labelled with the key name.
+// Implementations must be safe for concurrent use.
+type MetricsSink interface {
+	// IncCounter adds one to the counter name for key.
+	IncCounter(name, key string)
+	// ObserveHistogram records value in the histogram name for key.
+	ObserveHistogram(name, key string, value float64)
+}
+
+// MetricsObserver returns an Observer that turns events into MetricsSink
+// updates. Every event increments its counter; misses also record fn's
+// duration in seconds, and failed misses increment MetricErrors.
+//
+// Unlike Stats, the sink is typically shared by every cache in the
+// process, so the counters are process-wide.
+func MetricsObserver(sink MetricsSink) Observer {
+	return metricsObserver{sink: sink}
+}
+
+type metricsObserver struct {
+	sink MetricsSink
+}
+
+func (o metricsObserver) On(e EventData) {
+	switch e.Event {
+	case EventHit:
+		o.sink.IncCounter(MetricHits, e.Key)
+	case EventMiss:
+		o.sink.IncCounter(MetricMisses, e.Key)
+		o.sink.ObserveHistogram(MetricFnDuration, e.Key, e.Duration.Seconds())
+		if e.Err != nil {
+			o.sink.IncCounter(MetricErrors, e.Key)
+		}
+	case EventDedup:
+		o.sink.IncCounter(MetricDedups, e.Key)
+	case EventForget:
+		o.sink.IncCounter(MetricForgets, e.Key)
+	case EventNPlusOne:
+		o.sink.IncCounter(MetricNPlusOne, e.Key)
+	case EventEvict:
+		o.sink.IncCounter(MetricEvictions, e.Key)
+	case EventStale:
+		o.sink.IncCounter(MetricStaleHits, e.Key)
+	case EventRefreshError:
+		o.sink.IncCounter(MetricRefreshErrors, e.Key)
+	case EventPromote:
+		o.sink.IncCounter(MetricPromotions, e.Key)
+	}
+}
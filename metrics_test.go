@@ -0,0 +1,116 @@
+package callonce_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	callonce "github.com/probablyarth/callonce-go"
+)
+
+type sinkRecorder struct {
+	mu         sync.Mutex
+	counters   map[string]int
+	histograms map[string][]float64
+}
+
+func newSinkRecorder() *sinkRecorder {
+	return &sinkRecorder{counters: map[string]int{}, histograms: map[string][]float64{}}
+}
+
+func (s *sinkRecorder) IncCounter(name, key string) {
+	s.mu.Lock()
+	s.counters[name+"/"+key]++
+	s.mu.Unlock()
+}
+
+func (s *sinkRecorder) ObserveHistogram(name, key string, v float64) {
+	s.mu.Lock()
+	s.histograms[name+"/"+key] = append(s.histograms[name+"/"+key], v)
+	s.mu.Unlock()
+}
+
+func TestMetricsObserver(t *testing.T) {
+	sink := newSinkRecorder()
+	ctx := callonce.WithCache(context.Background(), callonce.WithObserver(callonce.MetricsObserver(sink)))
+
+	callonce.Get(ctx, func() (string, error) { return "v", nil }, callonce.L(testKey, "1"))
+	callonce.Get(ctx, func() (string, error) { return "v", nil }, callonce.L(testKey, "1"))
+	callonce.Get(ctx, func() (string, error) { return "", errors.New("fail") }, callonce.L(testKey, "2"))
+	callonce.Forget(ctx, callonce.L(testKey, "1"))
+
+	want := map[string]int{
+		"hits/string:test":    1,
+		"misses/string:test":  2,
+		"errors/string:test":  1,
+		"forgets/string:test": 1,
+	}
+	for k, n := range want {
+		if sink.counters[k] != n {
+			t.Errorf("%s = %d, want %d", k, sink.counters[k], n)
+		}
+	}
+	if n := len(sink.histograms["fn_duration_seconds/string:test"]); n != 2 {
+		t.Fatalf("got %d duration samples, want 2", n)
+	}
+}
+
+// expvarRuns keeps published names unique when tests run with -count > 1.
+var expvarRuns atomic.Int32
+
+func TestExpvarObserver(t *testing.T) {
+	name := fmt.Sprintf("callonce_test_expvar_%d", expvarRuns.Add(1))
+	obs := callonce.ExpvarObserver(name)
+	for i := 0; i < 2; i++ {
+		// Two caches share the same process-wide counters.
+		ctx := callonce.WithCache(context.Background(), callonce.WithObserver(obs))
+		callonce.Get(ctx, func() (string, error) { return "v", nil }, callonce.L(testKey, "1"))
+		callonce.Get(ctx, func() (string, error) { return "v", nil }, callonce.L(testKey, "1"))
+	}
+
+	var got struct {
+		Hits   map[string]int `json:"hits"`
+		Misses map[string]int `json:"misses"`
+		Fn     map[string]struct {
+			Count   int            `json:"count"`
+			Buckets map[string]int `json:"buckets"`
+		} `json:"fn_duration_seconds"`
+	}
+	if err := json.Unmarshal([]byte(expvar.Get(name).String()), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Hits["string:test"] != 2 || got.Misses["string:test"] != 2 {
+		t.Fatalf("got hits=%v misses=%v; want 2, 2", got.Hits, got.Misses)
+	}
+	h := got.Fn["string:test"]
+	if h.Count != 2 || h.Buckets["+Inf"] != 2 {
+		t.Fatalf("got histogram %+v, want count 2 and +Inf 2", h)
+	}
+}
+
+func TestExpvarSinkCopiesDefaultBuckets(t *testing.T) {
+	name := fmt.Sprintf("callonce_test_expvar_%d", expvarRuns.Add(1))
+	sink := callonce.NewExpvarSink(name)
+
+	saved := callonce.DefaultBuckets[0]
+	callonce.DefaultBuckets[0] = 100
+	defer func() { callonce.DefaultBuckets[0] = saved }()
+
+	sink.ObserveHistogram("h", "k", 0.0005)
+	var got struct {
+		H map[string]struct {
+			Buckets map[string]int `json:"buckets"`
+		} `json:"h"`
+	}
+	if err := json.Unmarshal([]byte(expvar.Get(name).String()), &got); err != nil {
+		t.Fatal(err)
+	}
+	if b := got.H["k"].Buckets; b["0.001"] != 1 {
+		t.Fatalf("got buckets %v, want the sink's own copy of the defaults", b)
+	}
+}
@@ -0,0 +1,67 @@
+package callonce
+
+import (
+	"context"
+	"sync"
+)
+
+// WithNPlusOneDetector watches misses per key and reports an
+// EventNPlusOne once more than threshold distinct identifiers of the same
+// key have been fetched individually within the cache. This usually means
+// a loop is calling Get per item where a batch fetch would do.
+//
+// Each key is reported at most once per cache. If onDetect is non-nil it
+// is called as well, which makes it easy to fail tests:
+//
+//	ctx := callonce.WithCache(ctx, callonce.WithNPlusOneDetector(10, func(key string, n int) {
+//	    t.Errorf("N+1: %d individual fetches of %s", n, key)
+//	}))
+func WithNPlusOneDetector(threshold int, onDetect func(key string, count int)) Option {
+	return func(cache *Cache) {
+		cache.nplusone = &nPlusOneDetector{
+			threshold: threshold,
+			onDetect:  onDetect,
+			seen:      make(map[string]map[string]struct{}),
+			reported:  make(map[string]bool),
+		}
+	}
+}
+
+type nPlusOneDetector struct {
+	threshold int
+	onDetect  func(key string, count int)
+
+	mu       sync.Mutex
+	seen     map[string]map[string]struct{}
+	reported map[string]bool
+}
+
+// observeMiss records a miss and reports keys that cross the threshold.
+func (c *Cache) observeMiss(ctx context.Context, keyName, identifier string) {
+	d := c.nplusone
+	if d == nil {
+		return
+	}
+
+	d.mu.Lock()
+	ids, ok := d.seen[keyName]
+	if !ok {
+		ids = make(map[string]struct{})
+		d.seen[keyName] = ids
+	}
+	ids[identifier] = struct{}{}
+	count := len(ids)
+	report := count > d.threshold && !d.reported[keyName]
+	if report {
+		d.reported[keyName] = true
+	}
+	d.mu.Unlock()
+
+	if !report {
+		return
+	}
+	c.emit(EventData{Event: EventNPlusOne, Key: keyName, Count: count, Context: ctx})
+	if d.onDetect != nil {
+		d.onDetect(keyName, count)
+	}
+}
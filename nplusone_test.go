@@ -0,0 +1,62 @@
+package callonce_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	callonce "github.com/probablyarth/callonce-go"
+)
+
+func TestNPlusOneDetector(t *testing.T) {
+	obs := &recordingObserver{}
+	var reports []string
+	ctx := callonce.WithCache(context.Background(),
+		callonce.WithObserver(obs),
+		callonce.WithNPlusOneDetector(3, func(key string, n int) {
+			reports = append(reports, fmt.Sprintf("%s=%d", key, n))
+		}),
+	)
+	key := callonce.NewKey[string]("item")
+	other := callonce.NewKey[string]("other")
+
+	for i := 0; i < 6; i++ {
+		id := fmt.Sprint(i)
+		callonce.Get(ctx, func() (string, error) { return id, nil }, callonce.L(key, id))
+		// Hits on the same identifier do not count.
+		callonce.Get(ctx, func() (string, error) { return id, nil }, callonce.L(key, id))
+	}
+	for i := 0; i < 3; i++ {
+		id := fmt.Sprint(i)
+		callonce.Get(ctx, func() (string, error) { return id, nil }, callonce.L(other, id))
+	}
+
+	if len(reports) != 1 || reports[0] != "string:item=4" {
+		t.Fatalf("reports = %v, want [string:item=4]", reports)
+	}
+
+	var detected []callonce.EventData
+	for _, e := range obs.snapshot() {
+		if e.Event == callonce.EventNPlusOne {
+			detected = append(detected, e)
+		}
+	}
+	if len(detected) != 1 || detected[0].Key != "string:item" || detected[0].Count != 4 {
+		t.Fatalf("got N+1 events %+v, want one for string:item with count 4", detected)
+	}
+}
+
+func TestNPlusOneDetectorRefetchSameIdentifier(t *testing.T) {
+	var reported bool
+	ctx := callonce.WithCache(context.Background(),
+		callonce.WithNPlusOneDetector(1, func(string, int) { reported = true }),
+	)
+
+	for i := 0; i < 5; i++ {
+		callonce.Get(ctx, func() (string, error) { return "v", nil }, callonce.L(testKey, "1"))
+		callonce.Forget(ctx, callonce.L(testKey, "1"))
+	}
+	if reported {
+		t.Fatal("refetching one identifier is not an N+1 pattern")
+	}
+}
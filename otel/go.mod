@@ -0,0 +1,24 @@
+module github.com/probablyarth/callonce-go/otel
+
+go 1.22
+
+// The replace builds this module against the working tree. Dependents
+// ignore it and use the required version below, which must be tagged
+// before this module; see CONTRIBUTING.md.
+replace github.com/probablyarth/callonce-go => ../
+
+require (
+	github.com/probablyarth/callonce-go v0.2.0
+	go.opentelemetry.io/otel v1.31.0
+	go.opentelemetry.io/otel/sdk v1.31.0
+	go.opentelemetry.io/otel/trace v1.31.0
+)
+
+require (
+	github.com/go-logr/logr v1.4.2 // indirect
+	github.com/go-logr/stdr v1.2.2 // indirect
+	github.com/google/uuid v1.6.0 // indirect
+	go.opentelemetry.io/otel/metric v1.31.0 // indirect
+	golang.org/x/sync v0.10.0 // indirect
+	golang.org/x/sys v0.26.0 // indirect
+)
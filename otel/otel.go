@@ -0,0 +1,96 @@
+// Package callonceotel provides an OpenTelemetry implementation of callonce.Tracer.
+//
+// Every fn execution on a miss becomes a span named "callonce.fetch",
+// started from the leader's context. Cache hits and dedup waiters get an
+// event and a link on their own current span, pointing at the span that
+// produced the value, so a waiter that blocked on someone else's call is
+// explained in the trace:
+//
+//	tracer := callonceotel.NewTracer(nil) // uses the global TracerProvider
+//	ctx := callonce.WithCache(r.Context(), callonce.WithTracer(tracer))
+package callonceotel
+
+import (
+	"context"
+
+	callonce "github.com/probablyarth/callonce-go"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	instrumentationName = "github.com/probablyarth/callonce-go/otel"
+
+	// SpanName is the name of the span started around fn.
+	SpanName = "callonce.fetch"
+
+	keyAttr        = attribute.Key("callonce.key")
+	identifierAttr = attribute.Key("callonce.identifier")
+	eventAttr      = attribute.Key("callonce.event")
+)
+
+// Tracer implements callonce.Tracer on top of an OpenTelemetry tracer.
+type Tracer struct {
+	tracer trace.Tracer
+}
+
+var _ callonce.Tracer = (*Tracer)(nil)
+
+// NewTracer returns a Tracer that creates spans with tp. If tp is nil the
+// global TracerProvider is used.
+func NewTracer(tp trace.TracerProvider) *Tracer {
+	if tp == nil {
+		tp = otel.GetTracerProvider()
+	}
+	return &Tracer{tracer: tp.Tracer(instrumentationName)}
+}
+
+// Start implements callonce.Tracer.
+func (t *Tracer) Start(ctx context.Context, key, identifier string) callonce.Span {
+	_, span := t.tracer.Start(ctx, SpanName,
+		trace.WithSpanKind(trace.SpanKindInternal),
+		trace.WithAttributes(keyAttr.String(key), identifierAttr.String(identifier)),
+	)
+	return fetchSpan{span: span}
+}
+
+// Link implements callonce.Tracer. It records an event named
+// "callonce.hit" or "callonce.dedup" on the span in ctx and, when the
+// leader was traced by this package, links to it.
+func (t *Tracer) Link(ctx context.Context, event callonce.Event, key, identifier string, leader callonce.Span) {
+	span := trace.SpanFromContext(ctx)
+	if !span.IsRecording() {
+		return
+	}
+
+	attrs := []attribute.KeyValue{
+		keyAttr.String(key),
+		identifierAttr.String(identifier),
+	}
+	if l, ok := leader.(fetchSpan); ok {
+		sc := l.span.SpanContext()
+		attrs = append(attrs,
+			attribute.String("callonce.leader.trace_id", sc.TraceID().String()),
+			attribute.String("callonce.leader.span_id", sc.SpanID().String()),
+		)
+		span.AddLink(trace.Link{
+			SpanContext: sc,
+			Attributes:  []attribute.KeyValue{eventAttr.String(event.String())},
+		})
+	}
+	span.AddEvent("callonce."+event.String(), trace.WithAttributes(attrs...))
+}
+
+type fetchSpan struct {
+	span trace.Span
+}
+
+func (s fetchSpan) End(err error) {
+	if err != nil {
+		s.span.RecordError(err)
+		s.span.SetStatus(codes.Error, err.Error())
+	}
+	s.span.End()
+}
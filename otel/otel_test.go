@@ -0,0 +1,84 @@
+package callonceotel_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	callonce "github.com/probablyarth/callonce-go"
+	callonceotel "github.com/probablyarth/callonce-go/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+var userKey = callonce.NewKey[string]("user")
+
+func TestSpanAroundFn(t *testing.T) {
+	rec := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec))
+
+	ctx := callonce.WithCache(context.Background(), callonce.WithTracer(callonceotel.NewTracer(tp)))
+	errBoom := errors.New("boom")
+
+	callonce.Get(ctx, func() (string, error) { return "", errBoom }, callonce.L(userKey, "1"))
+
+	spans := rec.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("got %d spans, want 1", len(spans))
+	}
+	s := spans[0]
+	if s.Name() != callonceotel.SpanName {
+		t.Fatalf("span name = %q, want %q", s.Name(), callonceotel.SpanName)
+	}
+	if s.Status().Code != codes.Error {
+		t.Fatalf("status = %v, want Error", s.Status().Code)
+	}
+	var gotKey bool
+	for _, a := range s.Attributes() {
+		if a.Key == "callonce.key" && a.Value.AsString() == "string:user" {
+			gotKey = true
+		}
+	}
+	if !gotKey {
+		t.Fatalf("missing callonce.key attribute, got %v", s.Attributes())
+	}
+}
+
+func TestHitLinksToLeader(t *testing.T) {
+	rec := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec))
+	tracer := tp.Tracer("test")
+
+	ctx := callonce.WithCache(context.Background(), callonce.WithTracer(callonceotel.NewTracer(tp)))
+
+	firstCtx, first := tracer.Start(ctx, "first")
+	callonce.Get(firstCtx, func() (string, error) { return "v", nil }, callonce.L(userKey, "1"))
+	first.End()
+
+	secondCtx, second := tracer.Start(ctx, "second")
+	callonce.Get(secondCtx, func() (string, error) { return "v", nil }, callonce.L(userKey, "1"))
+	second.End()
+
+	var fetch, caller sdktrace.ReadOnlySpan
+	for _, s := range rec.Ended() {
+		switch s.Name() {
+		case callonceotel.SpanName:
+			fetch = s
+		case "second":
+			caller = s
+		}
+	}
+	if fetch == nil || caller == nil {
+		t.Fatal("missing fetch or caller span")
+	}
+	if fetch.Parent().SpanID() != first.SpanContext().SpanID() {
+		t.Fatal("fetch span should be a child of the leader's span")
+	}
+	if len(caller.Links()) != 1 || caller.Links()[0].SpanContext.SpanID() != fetch.SpanContext().SpanID() {
+		t.Fatalf("caller links = %v, want a link to the fetch span", caller.Links())
+	}
+	if len(caller.Events()) != 1 || caller.Events()[0].Name != "callonce.hit" {
+		t.Fatalf("caller events = %v, want one callonce.hit", caller.Events())
+	}
+}
@@ -0,0 +1,42 @@
+package callonce
+
+import (
+	"context"
+	"runtime/pprof"
+	"runtime/trace"
+)
+
+// WithProfileLabels runs every fn inside pprof.Do with the labels
+// "callonce.key" (the key name) and "callonce.cache" (name), and inside a
+// runtime/trace task and region named after the key. CPU profiles can then
+// be filtered with
+//
+//	go tool pprof -tagfocus callonce.key=string:user
+//
+// and go tool trace shows the time spent per key. name identifies the
+// cache, e.g. a route or handler name.
+func WithProfileLabels(name string) Option {
+	return func(cache *Cache) {
+		cache.profile = true
+		cache.name = name
+	}
+}
+
+// callFn calls fn, wrapped in pprof labels and a trace region when
+// profiling is enabled for c.
+func callFn[T any](ctx context.Context, c *Cache, keyName string, fn func() (T, error)) (T, error) {
+	if !c.profile {
+		return fn()
+	}
+
+	var result T
+	var err error
+	pprof.Do(ctx, pprof.Labels("callonce.key", keyName, "callonce.cache", c.name), func(ctx context.Context) {
+		ctx, task := trace.NewTask(ctx, "callonce.fetch")
+		defer task.End()
+		trace.WithRegion(ctx, keyName, func() {
+			result, err = fn()
+		})
+	})
+	return result, err
+}
@@ -0,0 +1,46 @@
+package callonce_test
+
+import (
+	"bytes"
+	"context"
+	"runtime/pprof"
+	"strings"
+	"testing"
+
+	callonce "github.com/probablyarth/callonce-go"
+)
+
+func TestProfileLabelsAppliedToFn(t *testing.T) {
+	ctx := callonce.WithCache(context.Background(), callonce.WithProfileLabels("GET /user"))
+
+	var profile bytes.Buffer
+	v, err := callonce.Get(ctx, func() (string, error) {
+		// debug=1 prints the labels of every goroutine, including this one.
+		pprof.Lookup("goroutine").WriteTo(&profile, 1)
+		return "v", nil
+	}, callonce.L(testKey, "1"))
+	if err != nil || v != "v" {
+		t.Fatalf("got %q, %v; want v, nil", v, err)
+	}
+
+	out := profile.String()
+	for _, want := range []string{`"callonce.key":"string:test"`, `"callonce.cache":"GET /user"`} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("goroutine profile missing label %s", want)
+		}
+	}
+}
+
+func TestProfileLabelsNotAppliedByDefault(t *testing.T) {
+	ctx := callonce.WithCache(context.Background())
+
+	var profile bytes.Buffer
+	callonce.Get(ctx, func() (string, error) {
+		pprof.Lookup("goroutine").WriteTo(&profile, 1)
+		return "v", nil
+	}, callonce.L(testKey, "1"))
+
+	if strings.Contains(profile.String(), "callonce.key") {
+		t.Fatal("unexpected callonce labels without WithProfileLabels")
+	}
+}
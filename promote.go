@@ -0,0 +1,76 @@
+package callonce
+
+import "context"
+
+// MergeInto copies every unexpired value in c into parent, typically when a
+// sub-operation using a child cache finishes and its siblings will need the
+// same values. Values parent already holds unexpired are kept. parent emits
+// EventPromote for each value copied. It returns the number of values
+// copied, or 0 if c or parent is nil or parent is a snapshot made by
+// Detach.
+func (c *Cache) MergeInto(parent *Cache) int {
+	if c == nil || parent == nil || c == parent {
+		return 0
+	}
+
+	type entry struct {
+		key    StoreKey
+		stored any
+	}
+	var entries []entry
+	c.store.Range(func(key StoreKey, stored any) bool {
+		if _, ok := c.unexpired(stored); ok {
+			entries = append(entries, entry{key, stored})
+		}
+		return true
+	})
+
+	n := 0
+	for _, e := range entries {
+		if c.promote(context.Background(), parent, e.key, e.stored) {
+			n++
+		}
+	}
+	return n
+}
+
+// Promote copies the values of lookups from the cache in ctx into its
+// parent, like MergeInto but for selected keys. Lookups that are missing
+// or expired in the child, or already held by the parent, are skipped. It
+// returns the number of values copied.
+func Promote[T any](ctx context.Context, lookups ...Lookup[T]) int {
+	c := FromContext(ctx)
+	if c == nil || c.parent == nil {
+		return 0
+	}
+
+	n := 0
+	for _, l := range lookups {
+		stored, ok := c.store.Get(l.storeKey())
+		if !ok {
+			continue
+		}
+		if _, ok := c.unexpired(stored); ok && c.promote(ctx, c.parent, l.storeKey(), stored) {
+			n++
+		}
+	}
+	return n
+}
+
+// promote stores a value of c in parent unless parent is read-only or
+// already holds an unexpired value for key.
+func (c *Cache) promote(ctx context.Context, parent *Cache, key StoreKey, stored any) bool {
+	if parent.readOnly {
+		return false
+	}
+	if existing, ok := parent.store.Get(key); ok {
+		if _, ok := parent.unexpired(existing); ok {
+			return false
+		}
+	}
+	parent.store.Set(key, stored)
+	parent.unhide(key)
+	parent.setSpan(key, c.span(key))
+	parent.emit(EventData{Event: EventPromote, Key: key.Key, Identifier: key.Identifier, Context: ctx})
+	return true
+}
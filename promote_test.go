@@ -0,0 +1,62 @@
+package callonce_test
+
+import (
+	"context"
+	"testing"
+
+	callonce "github.com/probablyarth/callonce-go"
+)
+
+func TestMergeIntoCopiesChildValues(t *testing.T) {
+	rec := &recordingObserver{}
+	parent := callonce.WithCache(context.Background(), callonce.WithObserver(rec))
+	callonce.Get(parent, func() (string, error) { return "parent", nil }, callonce.L(testKey, "shared"))
+
+	child := callonce.WithChildCache(parent)
+	callonce.Get(child, func() (string, error) { return "child", nil }, callonce.L(testKey, "1"))
+	callonce.Get(child, func() (string, error) { return "child", nil }, callonce.L(testKey, "2"))
+	callonce.Forget(child, callonce.L(testKey, "shared"))
+	callonce.Get(child, func() (string, error) { return "child", nil }, callonce.L(testKey, "shared"))
+
+	if n := callonce.FromContext(child).MergeInto(callonce.FromContext(parent)); n != 2 {
+		t.Fatalf("merged %d values, want 2", n)
+	}
+
+	for id, want := range map[string]string{"1": "child", "2": "child", "shared": "parent"} {
+		v, _ := callonce.Get(parent, func() (string, error) { return "miss", nil }, callonce.L(testKey, id))
+		if v != want {
+			t.Errorf("parent %s: got %q, want %q", id, v, want)
+		}
+	}
+
+	promoted := 0
+	for _, e := range rec.snapshot() {
+		if e.Event == callonce.EventPromote {
+			promoted++
+		}
+	}
+	if promoted != 2 {
+		t.Fatalf("got %d promote events, want 2", promoted)
+	}
+}
+
+func TestPromoteSelectedLookups(t *testing.T) {
+	parent := callonce.WithCache(context.Background())
+	child := callonce.WithChildCache(parent)
+	callonce.Get(child, func() (string, error) { return "a", nil }, callonce.L(testKey, "a"))
+	callonce.Get(child, func() (string, error) { return "b", nil }, callonce.L(testKey, "b"))
+
+	if n := callonce.Promote(child, callonce.L(testKey, "a"), callonce.L(testKey, "missing")); n != 1 {
+		t.Fatalf("promoted %d values, want 1", n)
+	}
+
+	s := callonce.FromContext(parent).Stats()
+	if s.Entries != 1 || s.Promotions != 1 {
+		t.Fatalf("parent: got entries=%d promotions=%d; want 1, 1", s.Entries, s.Promotions)
+	}
+
+	// Without a parent there is nothing to promote into.
+	if n := callonce.Promote(parent, callonce.L(testKey, "a")); n != 0 {
+		t.Fatalf("promoted %d values from a root cache, want 0", n)
+	}
+}
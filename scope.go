@@ -0,0 +1,59 @@
+package callonce
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+)
+
+// BeginScope starts a transactional scope: values fetched and forgotten
+// through the returned context are applied to the cache in ctx by commit
+// and dropped by rollback, so a rolled-back transaction leaves no values
+// behind that never existed. It is built on Fork; only the first of commit
+// and rollback to be called has any effect, and after it the scoped context
+// uses the cache in ctx directly.
+func BeginScope(ctx context.Context) (scoped context.Context, commit, rollback func()) {
+	scoped, b := Fork(ctx)
+	var once sync.Once
+	commit = func() { once.Do(b.Commit) }
+	rollback = func() { once.Do(b.Discard) }
+	return scoped, commit, rollback
+}
+
+// ScopedTx is a *sql.Tx whose Commit and Rollback also end a cache scope
+// started by BeginTx.
+type ScopedTx struct {
+	*sql.Tx
+	commit   func()
+	rollback func()
+}
+
+// BeginTx starts a transaction on db together with a cache scope. Use the
+// returned context for queries and Get calls inside the transaction.
+func BeginTx(ctx context.Context, db *sql.DB, opts *sql.TxOptions) (context.Context, *ScopedTx, error) {
+	tx, err := db.BeginTx(ctx, opts)
+	if err != nil {
+		return ctx, nil, err
+	}
+	scoped, commit, rollback := BeginScope(ctx)
+	return scoped, &ScopedTx{Tx: tx, commit: commit, rollback: rollback}, nil
+}
+
+// Commit commits the transaction and, if that succeeds, the cache scope.
+// If the commit fails the scope is rolled back.
+func (t *ScopedTx) Commit() error {
+	if err := t.Tx.Commit(); err != nil {
+		t.rollback()
+		return err
+	}
+	t.commit()
+	return nil
+}
+
+// Rollback rolls back the transaction and the cache scope. It is safe to
+// defer after Commit: the scope is already committed and stays so.
+func (t *ScopedTx) Rollback() error {
+	err := t.Tx.Rollback()
+	t.rollback()
+	return err
+}
@@ -0,0 +1,91 @@
+package callonce_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"testing"
+
+	callonce "github.com/probablyarth/callonce-go"
+)
+
+func TestBeginScopeRollback(t *testing.T) {
+	ctx := callonce.WithCache(context.Background())
+	scoped, commit, rollback := callonce.BeginScope(ctx)
+
+	callonce.Get(scoped, func() (string, error) { return "uncommitted", nil }, callonce.L(testKey, "1"))
+	rollback()
+	commit() // no effect after rollback
+
+	v, _ := callonce.Get(ctx, func() (string, error) { return "fresh", nil }, callonce.L(testKey, "1"))
+	if v != "fresh" {
+		t.Fatalf("got %q, want rolled-back value to be gone", v)
+	}
+}
+
+func TestBeginScopeCommit(t *testing.T) {
+	ctx := callonce.WithCache(context.Background())
+	scoped, commit, rollback := callonce.BeginScope(ctx)
+
+	callonce.Get(scoped, func() (string, error) { return "committed", nil }, callonce.L(testKey, "1"))
+	commit()
+	rollback() // no effect after commit
+
+	v, _ := callonce.Get(ctx, func() (string, error) { return "fresh", nil }, callonce.L(testKey, "1"))
+	if v != "committed" {
+		t.Fatalf("got %q, want %q", v, "committed")
+	}
+}
+
+// fakeDriver is a database/sql driver whose transactions fail to commit
+// when failCommit is set.
+type fakeDriver struct{ failCommit bool }
+
+func (d *fakeDriver) Open(string) (driver.Conn, error) { return fakeConn{d}, nil }
+
+type connector struct{ d *fakeDriver }
+
+func (c connector) Connect(context.Context) (driver.Conn, error) { return fakeConn{c.d}, nil }
+func (c connector) Driver() driver.Driver                        { return c.d }
+
+type fakeConn struct{ d *fakeDriver }
+
+func (fakeConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (fakeConn) Close() error                        { return nil }
+func (c fakeConn) Begin() (driver.Tx, error)         { return fakeTx(c), nil }
+
+type fakeTx fakeConn
+
+func (tx fakeTx) Commit() error {
+	if tx.d.failCommit {
+		return errors.New("commit failed")
+	}
+	return nil
+}
+
+func (fakeTx) Rollback() error { return nil }
+
+func TestBeginTx(t *testing.T) {
+	for _, failCommit := range []bool{false, true} {
+		db := sql.OpenDB(connector{&fakeDriver{failCommit: failCommit}})
+		ctx := callonce.WithCache(context.Background())
+
+		txCtx, tx, err := callonce.BeginTx(ctx, db, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		callonce.Get(txCtx, func() (string, error) { return "in-tx", nil }, callonce.L(testKey, "1"))
+		err = tx.Commit()
+		tx.Rollback()
+		if (err != nil) != failCommit {
+			t.Fatalf("failCommit=%v: got commit error %v", failCommit, err)
+		}
+
+		v, _ := callonce.Get(ctx, func() (string, error) { return "fresh", nil }, callonce.L(testKey, "1"))
+		if want := map[bool]string{false: "in-tx", true: "fresh"}[failCommit]; v != want {
+			t.Fatalf("failCommit=%v: got %q, want %q", failCommit, v, want)
+		}
+		db.Close()
+	}
+}
@@ -0,0 +1,111 @@
+package callonce
+
+import (
+	"hash/maphash"
+	"sync"
+)
+
+// defaultShards is the number of shards NewShardedStore uses for n < 1.
+const defaultShards = 32
+
+var shardSeed = maphash.MakeSeed()
+
+// shardIndex maps key onto one of n shards, where n is a power of two.
+func shardIndex(key StoreKey, n int) int {
+	h := maphash.String(shardSeed, key.Identifier) ^ maphash.String(shardSeed, key.Key)*31
+	return int(h & uint64(n-1))
+}
+
+// NewShardedStore returns a Store that spreads keys over n independently
+// locked maps, so concurrent misses on different keys rarely contend. n is
+// rounded up to a power of two; values below 1 use the default of 32.
+func NewShardedStore(n int) Store {
+	if n < 1 {
+		n = defaultShards
+	}
+	return &shardedStore{shards: make([]storeShard, pow2(n))}
+}
+
+// WithShards spreads the cache's values and in-flight calls over n
+// independently locked shards, rounded up to a power of two, so that many
+// goroutines missing on different keys at once rarely contend. The default
+// is a single map and in-flight table, which is smaller and faster for
+// the modest concurrency of a typical request. A store set with WithStore
+// is kept; only the in-flight calls are sharded then. Values below 2 are
+// ignored.
+func WithShards(n int) Option {
+	return func(cache *Cache) {
+		if n > 1 {
+			cache.flights = make([]flightShard, pow2(n))
+		}
+	}
+}
+
+// pow2 rounds n up to a power of two.
+func pow2(n int) int {
+	size := 1
+	for size < n {
+		size <<= 1
+	}
+	return size
+}
+
+// flightFor returns the in-flight table for key.
+func (c *Cache) flightFor(key StoreKey) *flightShard {
+	if c.flights == nil {
+		return &c.flight
+	}
+	return &c.flights[shardIndex(key, len(c.flights))]
+}
+
+type shardedStore struct {
+	shards []storeShard
+}
+
+type storeShard struct {
+	mu sync.RWMutex
+	m  map[StoreKey]any
+}
+
+func (s *shardedStore) shard(key StoreKey) *storeShard {
+	return &s.shards[shardIndex(key, len(s.shards))]
+}
+
+func (s *shardedStore) Get(key StoreKey) (any, bool) {
+	sh := s.shard(key)
+	sh.mu.RLock()
+	v, ok := sh.m[key]
+	sh.mu.RUnlock()
+	return v, ok
+}
+
+func (s *shardedStore) Set(key StoreKey, value any) {
+	sh := s.shard(key)
+	sh.mu.Lock()
+	if sh.m == nil {
+		sh.m = make(map[StoreKey]any)
+	}
+	sh.m[key] = value
+	sh.mu.Unlock()
+}
+
+func (s *shardedStore) Delete(key StoreKey) {
+	sh := s.shard(key)
+	sh.mu.Lock()
+	delete(sh.m, key)
+	sh.mu.Unlock()
+}
+
+func (s *shardedStore) Range(fn func(key StoreKey, value any) bool) {
+	for i := range s.shards {
+		sh := &s.shards[i]
+		sh.mu.RLock()
+		for k, v := range sh.m {
+			if !fn(k, v) {
+				sh.mu.RUnlock()
+				return
+			}
+		}
+		sh.mu.RUnlock()
+	}
+}
@@ -0,0 +1,81 @@
+package callonce_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	callonce "github.com/probablyarth/callonce-go"
+)
+
+func TestShardedStore(t *testing.T) {
+	s := callonce.NewShardedStore(5) // rounded up to 8
+	const n = 200
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			s.Set(callonce.StoreKey{Key: "k", Identifier: fmt.Sprint(i)}, i)
+		}(i)
+	}
+	wg.Wait()
+
+	for i := 0; i < n; i++ {
+		v, ok := s.Get(callonce.StoreKey{Key: "k", Identifier: fmt.Sprint(i)})
+		if !ok || v != i {
+			t.Fatalf("Get(%d) = %v, %v", i, v, ok)
+		}
+	}
+	if _, ok := s.Get(callonce.StoreKey{Key: "other", Identifier: "0"}); ok {
+		t.Fatal("keys with different names must not collide")
+	}
+
+	s.Delete(callonce.StoreKey{Key: "k", Identifier: "0"})
+	var count int
+	s.Range(func(callonce.StoreKey, any) bool {
+		count++
+		return true
+	})
+	if count != n-1 {
+		t.Fatalf("range visited %d entries, want %d", count, n-1)
+	}
+
+	count = 0
+	s.Range(func(callonce.StoreKey, any) bool {
+		count++
+		return count < 3
+	})
+	if count != 3 {
+		t.Fatalf("range visited %d entries after stopping, want 3", count)
+	}
+}
+
+func TestWithShards(t *testing.T) {
+	ctx := callonce.WithCache(context.Background(), callonce.WithShards(8))
+
+	var calls atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			callonce.Get(ctx, func() (string, error) {
+				calls.Add(1)
+				time.Sleep(time.Millisecond)
+				return "v", nil
+			}, callonce.L(testKey, fmt.Sprint(i%10)))
+		}(i)
+	}
+	wg.Wait()
+
+	if n := calls.Load(); n != 10 {
+		t.Fatalf("fn called %d times, want 10", n)
+	}
+	if s := callonce.FromContext(ctx).Stats(); s.Entries != 10 {
+		t.Fatalf("entries = %d, want 10", s.Entries)
+	}
+}
@@ -0,0 +1,86 @@
+package callonce
+
+import (
+	"context"
+	"log/slog"
+)
+
+// SlogOptions configures the Observer returned by SlogObserver. A nil
+// *SlogOptions is equivalent to the zero value.
+type SlogOptions struct {
+	// Levels sets the log level per event type. Events without an entry
+	// are logged at slog.LevelDebug.
+	Levels map[Event]slog.Leveler
+
+	// ErrorLevel is the level for misses whose fn returned an error.
+	// Defaults to slog.LevelError.
+	ErrorLevel slog.Leveler
+
+	// Message is the log message. Defaults to "callonce".
+	Message string
+
+	// ContextAttrs, if set, is called with the context of the Get or
+	// Forget call to add request-scoped attributes such as a request ID.
+	ContextAttrs func(ctx context.Context) []slog.Attr
+}
+
+// SlogObserver returns an Observer that logs every event to logger with
+// the attributes event, key and identifier, plus duration and error for
+// misses and count for N+1 reports.
+func SlogObserver(logger *slog.Logger, opts *SlogOptions) Observer {
+	o := &slogObserver{logger: logger}
+	if opts != nil {
+		o.opts = *opts
+	}
+	if o.opts.ErrorLevel == nil {
+		o.opts.ErrorLevel = slog.LevelError
+	}
+	if o.opts.Message == "" {
+		o.opts.Message = "callonce"
+	}
+	return o
+}
+
+type slogObserver struct {
+	logger *slog.Logger
+	opts   SlogOptions
+}
+
+func (o *slogObserver) On(e EventData) {
+	ctx := e.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	level := slog.LevelDebug
+	if l, ok := o.opts.Levels[e.Event]; ok {
+		level = l.Level()
+	}
+	if e.Err != nil {
+		level = o.opts.ErrorLevel.Level()
+	}
+	if !o.logger.Enabled(ctx, level) {
+		return
+	}
+
+	attrs := make([]slog.Attr, 0, 6)
+	attrs = append(attrs,
+		slog.String("event", e.Event.String()),
+		slog.String("key", e.Key),
+		slog.String("identifier", e.Identifier),
+	)
+	if e.Event == EventMiss {
+		attrs = append(attrs, slog.Duration("duration", e.Duration))
+	}
+	if e.Err != nil {
+		attrs = append(attrs, slog.Any("error", e.Err))
+	}
+	if e.Event == EventNPlusOne {
+		attrs = append(attrs, slog.Int("count", e.Count))
+	}
+	if o.opts.ContextAttrs != nil {
+		attrs = append(attrs, o.opts.ContextAttrs(ctx)...)
+	}
+
+	o.logger.LogAttrs(ctx, level, o.opts.Message, attrs...)
+}
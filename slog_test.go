@@ -0,0 +1,97 @@
+package callonce_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+
+	callonce "github.com/probablyarth/callonce-go"
+)
+
+type requestIDKey struct{}
+
+func decodeLines(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+	var out []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var m map[string]any
+		if err := json.Unmarshal([]byte(line), &m); err != nil {
+			t.Fatalf("invalid JSON line %q: %v", line, err)
+		}
+		out = append(out, m)
+	}
+	return out
+}
+
+func TestSlogObserverAttributes(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	obs := callonce.SlogObserver(logger, &callonce.SlogOptions{
+		ContextAttrs: func(ctx context.Context) []slog.Attr {
+			id, _ := ctx.Value(requestIDKey{}).(string)
+			return []slog.Attr{slog.String("request_id", id)}
+		},
+	})
+
+	ctx := context.WithValue(context.Background(), requestIDKey{}, "req-1")
+	ctx = callonce.WithCache(ctx, callonce.WithObserver(obs))
+
+	callonce.Get(ctx, func() (string, error) { return "v", nil }, callonce.L(testKey, "1"))
+	callonce.Get(ctx, func() (string, error) { return "v", nil }, callonce.L(testKey, "1"))
+
+	lines := decodeLines(t, &buf)
+	if len(lines) != 2 {
+		t.Fatalf("got %d log lines, want 2", len(lines))
+	}
+	miss, hit := lines[0], lines[1]
+	if miss["event"] != "miss" || hit["event"] != "hit" {
+		t.Fatalf("events = %v, %v; want miss, hit", miss["event"], hit["event"])
+	}
+	if miss["key"] != "string:test" || miss["identifier"] != "1" {
+		t.Fatalf("got key=%v identifier=%v", miss["key"], miss["identifier"])
+	}
+	if _, ok := miss["duration"]; !ok {
+		t.Fatal("miss is missing the duration attribute")
+	}
+	if _, ok := hit["duration"]; ok {
+		t.Fatal("hit should not carry a duration attribute")
+	}
+	if hit["request_id"] != "req-1" {
+		t.Fatalf("request_id = %v, want req-1", hit["request_id"])
+	}
+	if miss["level"] != "DEBUG" {
+		t.Fatalf("level = %v, want DEBUG", miss["level"])
+	}
+}
+
+func TestSlogObserverLevels(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil)) // Info and above.
+	obs := callonce.SlogObserver(logger, &callonce.SlogOptions{
+		Levels:  map[callonce.Event]slog.Leveler{callonce.EventMiss: slog.LevelInfo},
+		Message: "cache",
+	})
+	ctx := callonce.WithCache(context.Background(), callonce.WithObserver(obs))
+
+	callonce.Get(ctx, func() (string, error) { return "v", nil }, callonce.L(testKey, "1"))
+	callonce.Get(ctx, func() (string, error) { return "v", nil }, callonce.L(testKey, "1")) // hit, debug
+	callonce.Get(ctx, func() (string, error) { return "", errors.New("boom") }, callonce.L(testKey, "2"))
+
+	lines := decodeLines(t, &buf)
+	if len(lines) != 2 {
+		t.Fatalf("got %d log lines, want 2", len(lines))
+	}
+	if lines[0]["level"] != "INFO" || lines[0]["msg"] != "cache" {
+		t.Fatalf("got level=%v msg=%v; want INFO cache", lines[0]["level"], lines[0]["msg"])
+	}
+	if lines[1]["level"] != "ERROR" || lines[1]["error"] != "boom" {
+		t.Fatalf("got level=%v error=%v; want ERROR boom", lines[1]["level"], lines[1]["error"])
+	}
+}
@@ -0,0 +1,173 @@
+package callonce
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Counters holds event counts for a Cache or for a single key.
+type Counters struct {
+	Hits    uint64
+	Misses  uint64
+	Dedups  uint64
+	Errors  uint64
+	Forgets uint64
+	// Evictions counts values evicted by WithMaxEntries or WithMaxBytes.
+	Evictions uint64
+	// StaleHits counts expired values returned while being refreshed, and
+	// RefreshErrors the background refreshes that failed.
+	StaleHits     uint64
+	RefreshErrors uint64
+	// Promotions counts values copied into the cache from a child.
+	Promotions uint64
+	// FnTime is the total time spent inside fn.
+	FnTime time.Duration
+}
+
+// Stats is a point-in-time snapshot of a Cache's activity.
+type Stats struct {
+	Counters
+	// Entries is the number of values currently stored.
+	Entries int
+	// Keys breaks the counters down by key name. It is empty unless the
+	// cache was created with WithKeyStats or WithSummaryObserver.
+	Keys map[string]Counters
+}
+
+// Saved returns the number of fn invocations avoided by hits and dedups.
+func (c Counters) Saved() uint64 {
+	return c.Hits + c.Dedups
+}
+
+type counters struct {
+	hits        atomic.Uint64
+	misses      atomic.Uint64
+	dedups      atomic.Uint64
+	errors      atomic.Uint64
+	forgets     atomic.Uint64
+	evicts      atomic.Uint64
+	stale       atomic.Uint64
+	refreshErrs atomic.Uint64
+	promotions  atomic.Uint64
+	fnTime      atomic.Int64
+}
+
+func (s *counters) add(e *EventData) {
+	switch e.Event {
+	case EventHit:
+		s.hits.Add(1)
+	case EventMiss:
+		s.misses.Add(1)
+		s.fnTime.Add(int64(e.Duration))
+		if e.Err != nil {
+			s.errors.Add(1)
+		}
+	case EventDedup:
+		s.dedups.Add(1)
+	case EventForget:
+		s.forgets.Add(1)
+	case EventEvict:
+		s.evicts.Add(1)
+	case EventStale:
+		s.stale.Add(1)
+	case EventRefreshError:
+		s.refreshErrs.Add(1)
+	case EventPromote:
+		s.promotions.Add(1)
+	}
+}
+
+func (s *counters) snapshot() Counters {
+	return Counters{
+		Hits:          s.hits.Load(),
+		Misses:        s.misses.Load(),
+		Dedups:        s.dedups.Load(),
+		Errors:        s.errors.Load(),
+		Forgets:       s.forgets.Load(),
+		Evictions:     s.evicts.Load(),
+		StaleHits:     s.stale.Load(),
+		RefreshErrors: s.refreshErrs.Load(),
+		Promotions:    s.promotions.Load(),
+		FnTime:        time.Duration(s.fnTime.Load()),
+	}
+}
+
+// WithKeyStats makes the cache keep a per-key breakdown of its counters in
+// Stats.Keys. It costs a map lookup per event, so it is off by default.
+func WithKeyStats() Option {
+	return func(cache *Cache) {
+		cache.stats.perKey = true
+	}
+}
+
+// cacheStats keeps per-key counters in a copy-on-write map: key names are
+// few and added rarely, and a plain map lookup keeps the hit path shallow
+// enough that short-lived goroutines don't grow their stacks.
+type cacheStats struct {
+	total  counters
+	perKey bool
+	mu     sync.Mutex // serializes copies of keys
+	keys   atomic.Pointer[map[string]*counters]
+}
+
+func (s *cacheStats) add(e *EventData) {
+	s.total.add(e)
+	if !s.perKey {
+		return
+	}
+	if m := s.keys.Load(); m != nil {
+		if k := (*m)[e.Key]; k != nil {
+			k.add(e)
+			return
+		}
+	}
+	s.key(e.Key).add(e)
+}
+
+// key returns the counters for name, adding them if needed.
+func (s *cacheStats) key(name string) *counters {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var old map[string]*counters
+	if m := s.keys.Load(); m != nil {
+		old = *m
+	}
+	if k := old[name]; k != nil {
+		return k
+	}
+	m := make(map[string]*counters, len(old)+1)
+	for name, k := range old {
+		m[name] = k
+	}
+	k := new(counters)
+	m[name] = k
+	s.keys.Store(&m)
+	return k
+}
+
+// Stats returns a snapshot of the cache's counters. Counters are updated
+// atomically, so a snapshot taken while Get calls are running may be
+// slightly behind. It returns the zero Stats if c is nil.
+func (c *Cache) Stats() Stats {
+	if c == nil {
+		return Stats{}
+	}
+
+	s := Stats{
+		Counters: c.stats.total.snapshot(),
+		Keys:     make(map[string]Counters),
+	}
+	if m := c.stats.keys.Load(); m != nil {
+		for k, v := range *m {
+			s.Keys[k] = v.snapshot()
+		}
+	}
+
+	c.store.Range(func(StoreKey, any) bool {
+		s.Entries++
+		return true
+	})
+
+	return s
+}
@@ -0,0 +1,91 @@
+package callonce_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	callonce "github.com/probablyarth/callonce-go"
+)
+
+func TestStatsCounters(t *testing.T) {
+	ctx := callonce.WithCache(context.Background(), callonce.WithKeyStats())
+	user := callonce.NewKey[string]("user")
+	org := callonce.NewKey[string]("org")
+
+	slow := func() (string, error) {
+		time.Sleep(time.Millisecond)
+		return "v", nil
+	}
+
+	callonce.Get(ctx, slow, callonce.L(user, "1"))
+	callonce.Get(ctx, slow, callonce.L(user, "1"))
+	callonce.Get(ctx, slow, callonce.L(user, "1"))
+	callonce.Get(ctx, slow, callonce.L(org, "a"))
+	callonce.Get(ctx, func() (string, error) { return "", errors.New("fail") }, callonce.L(org, "b"))
+	callonce.Forget(ctx, callonce.L(user, "1"))
+
+	s := callonce.FromContext(ctx).Stats()
+	if s.Hits != 2 || s.Misses != 3 || s.Errors != 1 || s.Forgets != 1 {
+		t.Fatalf("got hits=%d misses=%d errors=%d forgets=%d; want 2, 3, 1, 1",
+			s.Hits, s.Misses, s.Errors, s.Forgets)
+	}
+	if s.Entries != 1 {
+		t.Fatalf("entries = %d, want 1", s.Entries)
+	}
+	if s.FnTime < 2*time.Millisecond {
+		t.Fatalf("fn time = %v, want at least 2ms", s.FnTime)
+	}
+	if s.Saved() != 2 {
+		t.Fatalf("saved = %d, want 2", s.Saved())
+	}
+
+	u := s.Keys["string:user"]
+	if u.Hits != 2 || u.Misses != 1 || u.Forgets != 1 {
+		t.Fatalf("user key: got hits=%d misses=%d forgets=%d; want 2, 1, 1", u.Hits, u.Misses, u.Forgets)
+	}
+	o := s.Keys["string:org"]
+	if o.Misses != 2 || o.Errors != 1 {
+		t.Fatalf("org key: got misses=%d errors=%d; want 2, 1", o.Misses, o.Errors)
+	}
+}
+
+func TestStatsKeysOptIn(t *testing.T) {
+	ctx := callonce.WithCache(context.Background())
+	callonce.Get(ctx, func() (string, error) { return "v", nil }, callonce.L(testKey, "1"))
+
+	s := callonce.FromContext(ctx).Stats()
+	if s.Misses != 1 || len(s.Keys) != 0 {
+		t.Fatalf("got misses=%d keys=%v; want 1 and no per-key breakdown", s.Misses, s.Keys)
+	}
+}
+
+func TestStatsNilCache(t *testing.T) {
+	s := callonce.FromContext(context.Background()).Stats()
+	if s.Hits != 0 || s.Keys != nil {
+		t.Fatalf("got %+v, want zero Stats", s)
+	}
+}
+
+func TestObserverMissCarriesDurationAndError(t *testing.T) {
+	obs := &recordingObserver{}
+	ctx := callonce.WithCache(context.Background(), callonce.WithObserver(obs))
+	errBoom := errors.New("boom")
+
+	callonce.Get(ctx, func() (string, error) {
+		time.Sleep(time.Millisecond)
+		return "", errBoom
+	}, callonce.L(testKey, "1"))
+
+	events := obs.snapshot()
+	if len(events) != 1 {
+		t.Fatalf("got %d events, want 1", len(events))
+	}
+	if !errors.Is(events[0].Err, errBoom) {
+		t.Fatalf("err = %v, want %v", events[0].Err, errBoom)
+	}
+	if events[0].Duration < time.Millisecond {
+		t.Fatalf("duration = %v, want at least 1ms", events[0].Duration)
+	}
+}
@@ -0,0 +1,77 @@
+package callonce
+
+import "sync"
+
+// StoreKey identifies a value in a Store.
+type StoreKey struct {
+	// Key is the key name, as reported in EventData.Key.
+	Key        string
+	Identifier string
+}
+
+// Store holds a Cache's values. The default is NewMapStore; use
+// WithStore to plug in a different implementation, for example a
+// size-bounded, instrumented or persistent one.
+//
+// Values are opaque to the store and must be returned exactly as they were
+// set. Implementations must be safe for concurrent use. Each Cache needs
+// its own Store.
+type Store interface {
+	// Get returns the value stored under key.
+	Get(key StoreKey) (value any, ok bool)
+	// Set stores value under key, replacing any previous value.
+	Set(key StoreKey, value any)
+	// Delete removes key. Deleting a missing key is a no-op.
+	Delete(key StoreKey)
+	// Range calls fn for each stored value until fn returns false. fn must
+	// not call other methods of the store.
+	Range(fn func(key StoreKey, value any) bool)
+}
+
+// WithStore makes the cache keep its values in s instead of the default
+// map.
+func WithStore(s Store) Option {
+	return func(cache *Cache) {
+		cache.store = s
+	}
+}
+
+// NewMapStore returns a Store backed by a single map guarded by a
+// read-write mutex. It is the default store.
+func NewMapStore() Store {
+	return &mapStore{m: make(map[StoreKey]any)}
+}
+
+type mapStore struct {
+	mu sync.RWMutex
+	m  map[StoreKey]any
+}
+
+func (s *mapStore) Get(key StoreKey) (any, bool) {
+	s.mu.RLock()
+	v, ok := s.m[key]
+	s.mu.RUnlock()
+	return v, ok
+}
+
+func (s *mapStore) Set(key StoreKey, value any) {
+	s.mu.Lock()
+	s.m[key] = value
+	s.mu.Unlock()
+}
+
+func (s *mapStore) Delete(key StoreKey) {
+	s.mu.Lock()
+	delete(s.m, key)
+	s.mu.Unlock()
+}
+
+func (s *mapStore) Range(fn func(key StoreKey, value any) bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for k, v := range s.m {
+		if !fn(k, v) {
+			return
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package callonce_test
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	callonce "github.com/probablyarth/callonce-go"
+)
+
+// countingStore wraps the default store and counts calls.
+type countingStore struct {
+	callonce.Store
+	gets, sets, deletes atomic.Int32
+}
+
+func (s *countingStore) Get(k callonce.StoreKey) (any, bool) {
+	s.gets.Add(1)
+	return s.Store.Get(k)
+}
+
+func (s *countingStore) Set(k callonce.StoreKey, v any) {
+	s.sets.Add(1)
+	s.Store.Set(k, v)
+}
+
+func (s *countingStore) Delete(k callonce.StoreKey) {
+	s.deletes.Add(1)
+	s.Store.Delete(k)
+}
+
+func TestWithStore(t *testing.T) {
+	store := &countingStore{Store: callonce.NewMapStore()}
+	ctx := callonce.WithCache(context.Background(), callonce.WithStore(store))
+
+	callonce.Get(ctx, func() (string, error) { return "v", nil }, callonce.L(testKey, "1"))
+	v, _ := callonce.Get(ctx, func() (string, error) { return "other", nil }, callonce.L(testKey, "1"))
+	callonce.Forget(ctx, callonce.L(testKey, "1"))
+
+	if v != "v" {
+		t.Fatalf("got %q, want cached value", v)
+	}
+	if store.sets.Load() != 1 || store.deletes.Load() != 1 || store.gets.Load() == 0 {
+		t.Fatalf("got gets=%d sets=%d deletes=%d", store.gets.Load(), store.sets.Load(), store.deletes.Load())
+	}
+}
+
+func TestMapStoreRange(t *testing.T) {
+	s := callonce.NewMapStore()
+	for _, id := range []string{"a", "b", "c"} {
+		s.Set(callonce.StoreKey{Key: "k", Identifier: id}, id)
+	}
+	s.Delete(callonce.StoreKey{Key: "k", Identifier: "b"})
+
+	seen := map[string]any{}
+	s.Range(func(k callonce.StoreKey, v any) bool {
+		seen[k.Identifier] = v
+		return true
+	})
+	if len(seen) != 2 || seen["a"] != "a" || seen["c"] != "c" {
+		t.Fatalf("range saw %v, want a and c", seen)
+	}
+
+	var n int
+	s.Range(func(callonce.StoreKey, any) bool {
+		n++
+		return false
+	})
+	if n != 1 {
+		t.Fatalf("range visited %d entries after returning false, want 1", n)
+	}
+}
+
+func TestMapStoreConcurrent(t *testing.T) {
+	s := callonce.NewMapStore()
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			k := callonce.StoreKey{Key: "k", Identifier: string(rune('a' + i))}
+			for j := 0; j < 100; j++ {
+				s.Set(k, j)
+				s.Get(k)
+				s.Range(func(callonce.StoreKey, any) bool { return true })
+				s.Delete(k)
+			}
+		}(i)
+	}
+	wg.Wait()
+}
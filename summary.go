@@ -0,0 +1,82 @@
+package callonce
+
+import (
+	"context"
+	"time"
+)
+
+// SummaryObserver receives a single Summary when the context passed to
+// WithCache is done. OnSummary runs on its own goroutine.
+type SummaryObserver interface {
+	OnSummary(summary Summary)
+}
+
+// Summary describes a cache's activity over its lifetime.
+type Summary struct {
+	Counters
+	// Entries is the number of values stored when the summary was taken.
+	Entries int
+	// Elapsed is the time since the cache was created.
+	Elapsed time.Duration
+	// TimeSaved estimates the fn time avoided by hits and dedups.
+	TimeSaved time.Duration
+	// Keys breaks the summary down by key name. Summary leaves it empty
+	// unless the cache keeps per-key counters; see WithKeyStats.
+	Keys map[string]KeySummary
+}
+
+// KeySummary describes the activity of a single key.
+type KeySummary struct {
+	Counters
+	// TimeSaved estimates the fn time avoided by hits and dedups, using
+	// the key's average fn duration.
+	TimeSaved time.Duration
+}
+
+// WithSummaryObserver delivers a Summary to o once the context passed to
+// WithCache is canceled or times out. Nothing is delivered for contexts
+// that are never done. It implies WithKeyStats.
+func WithSummaryObserver(o SummaryObserver) Option {
+	return func(cache *Cache) {
+		cache.summary = o
+		cache.stats.perKey = true
+	}
+}
+
+// Summary returns a Summary of the cache's activity so far. It returns the
+// zero Summary if c is nil.
+func (c *Cache) Summary() Summary {
+	if c == nil {
+		return Summary{}
+	}
+
+	s := c.Stats()
+	sum := Summary{
+		Counters: s.Counters,
+		Entries:  s.Entries,
+		Elapsed:  time.Since(c.created),
+		Keys:     make(map[string]KeySummary, len(s.Keys)),
+	}
+	for name, k := range s.Keys {
+		ks := KeySummary{Counters: k, TimeSaved: estimateSaved(k)}
+		sum.TimeSaved += ks.TimeSaved
+		sum.Keys[name] = ks
+	}
+	return sum
+}
+
+func estimateSaved(c Counters) time.Duration {
+	if c.Misses == 0 {
+		return 0
+	}
+	return c.FnTime / time.Duration(c.Misses) * time.Duration(c.Saved())
+}
+
+func (c *Cache) watch(ctx context.Context) {
+	if c.summary == nil {
+		return
+	}
+	context.AfterFunc(ctx, func() {
+		c.summary.OnSummary(c.Summary())
+	})
+}
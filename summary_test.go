@@ -0,0 +1,68 @@
+package callonce_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	callonce "github.com/probablyarth/callonce-go"
+)
+
+type summaryRecorder chan callonce.Summary
+
+func (r summaryRecorder) OnSummary(s callonce.Summary) { r <- s }
+
+func TestSummaryDeliveredWhenContextDone(t *testing.T) {
+	rec := make(summaryRecorder, 1)
+	parent, cancel := context.WithCancel(context.Background())
+	ctx := callonce.WithCache(parent, callonce.WithSummaryObserver(rec))
+	key := callonce.NewKey[string]("summary")
+
+	fn := func() (string, error) {
+		time.Sleep(2 * time.Millisecond)
+		return "v", nil
+	}
+	callonce.Get(ctx, fn, callonce.L(key, "1"))
+	callonce.Get(ctx, fn, callonce.L(key, "1"))
+	callonce.Get(ctx, fn, callonce.L(key, "1"))
+
+	select {
+	case <-rec:
+		t.Fatal("summary delivered before the context was done")
+	default:
+	}
+
+	cancel()
+
+	var s callonce.Summary
+	select {
+	case s = <-rec:
+	case <-time.After(time.Second):
+		t.Fatal("summary not delivered")
+	}
+
+	if s.Misses != 1 || s.Hits != 2 {
+		t.Fatalf("got misses=%d hits=%d; want 1, 2", s.Misses, s.Hits)
+	}
+	k, ok := s.Keys["string:summary"]
+	if !ok {
+		t.Fatalf("missing key summary, got %v", s.Keys)
+	}
+	if k.TimeSaved < 4*time.Millisecond {
+		t.Fatalf("saved = %v, want at least 4ms", k.TimeSaved)
+	}
+	if s.TimeSaved != k.TimeSaved {
+		t.Fatalf("total saved = %v, want %v", s.TimeSaved, k.TimeSaved)
+	}
+	if s.Elapsed < s.FnTime {
+		t.Fatalf("elapsed %v shorter than fn time %v", s.Elapsed, s.FnTime)
+	}
+}
+
+func TestSummaryNoMissesSavesNothing(t *testing.T) {
+	ctx := callonce.WithCache(context.Background())
+	s := callonce.FromContext(ctx).Summary()
+	if s.TimeSaved != 0 || len(s.Keys) != 0 {
+		t.Fatalf("got %+v, want empty summary", s)
+	}
+}
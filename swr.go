@@ -0,0 +1,85 @@
+package callonce
+
+import (
+	"context"
+	"time"
+)
+
+// WithStaleWhileRevalidate makes Get return an expired value immediately
+// instead of waiting for fn, and refresh it in the background. Refreshes
+// go through the same in-flight calls as misses, so at most one runs per
+// key and concurrent misses join it.
+//
+// Values more than maxStale past their expiry are treated as misses again.
+// A maxStale below or equal to 0 serves stale values regardless of age.
+// It has no effect without WithTTL or WithKeyTTL.
+func WithStaleWhileRevalidate(maxStale time.Duration) Option {
+	return func(cache *Cache) {
+		cache.swr = true
+		cache.maxStale = maxStale
+	}
+}
+
+// servable reports whether an expired stored value may still be served.
+func (c *Cache) servable(stored any) bool {
+	e, ok := stored.(*expiring)
+	return ok && (c.maxStale <= 0 || c.now().Before(e.expires.Add(c.maxStale)))
+}
+
+// stale returns the first expired but servable value among lookups and
+// starts a background refresh of all of them.
+func stale[T any](ctx context.Context, c *Cache, fn func() (T, error), lookups []Lookup[T]) (any, bool) {
+	for _, lookup := range lookups {
+		stored, owner, ok := c.lookup(lookup.storeKey())
+		if !ok || !owner.servable(stored) {
+			continue
+		}
+		c.emit(EventData{Event: EventStale, Key: lookup.Key.name, Identifier: lookup.Identifier, Context: ctx})
+		c.link(ctx, EventStale, lookup.Key.name, lookup.Identifier, c.span(lookup.storeKey()))
+		refresh(ctx, c, fn, lookups)
+		return storedValue(stored), true
+	}
+	return nil, false
+}
+
+// refresh runs fn for lookups in a new goroutine unless a call for
+// lookups[0] is already in flight. The refresh outlives ctx's
+// cancellation; a failure is reported as EventRefreshError and leaves the
+// stale value in place.
+func refresh[T any](ctx context.Context, c *Cache, fn func() (T, error), lookups []Lookup[T]) {
+	key := lookups[0].storeKey()
+	fl := c.target().flightFor(key)
+
+	cl, leader := fl.join(key)
+	if !leader {
+		cl.release()
+		return
+	}
+
+	// Copy lookups so that the caller's variadic slice can stay on the
+	// stack.
+	owned := append([]Lookup[T](nil), lookups...)
+	go func() {
+		finished := false
+		defer func() {
+			if !finished {
+				// fn called runtime.Goexit; unblock the waiters.
+				cl.err = errGoexit
+				fl.finish(key, cl)
+				cl.release()
+			}
+		}()
+		lead(context.WithoutCancel(ctx), c, cl, fn, owned)
+		finished = true
+		fl.finish(key, cl)
+
+		err := cl.err
+		if cl.panic != nil {
+			err = cl.panic
+		}
+		cl.release()
+		if err != nil {
+			c.emit(EventData{Event: EventRefreshError, Key: key.Key, Identifier: key.Identifier, Err: err, Context: ctx})
+		}
+	}()
+}
@@ -0,0 +1,152 @@
+package callonce_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	callonce "github.com/probablyarth/callonce-go"
+)
+
+// eventually polls cond until it holds or a second has passed.
+func eventually(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met within 1s")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestStaleWhileRevalidate(t *testing.T) {
+	clock := newFakeClock()
+	ctx := callonce.WithCache(context.Background(),
+		callonce.WithTTL(time.Minute),
+		callonce.WithStaleWhileRevalidate(0),
+		callonce.WithClock(clock),
+	)
+	key := callonce.NewKey[int]("version")
+
+	var calls atomic.Int32
+	release := make(chan struct{})
+	fn := func() (int, error) {
+		n := calls.Add(1)
+		if n > 1 {
+			<-release
+		}
+		return int(n), nil
+	}
+
+	callonce.Get(ctx, fn, callonce.L(key, "1"))
+	clock.Advance(time.Hour)
+
+	// Every caller gets the stale value without waiting, and only one
+	// refresh is started.
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if v, err := callonce.Get(ctx, fn, callonce.L(key, "1")); v != 1 || err != nil {
+				t.Errorf("got %d, %v; want stale 1, nil", v, err)
+			}
+		}()
+	}
+	wg.Wait()
+	close(release)
+
+	eventually(t, func() bool {
+		v, _ := callonce.Get(ctx, fn, callonce.L(key, "1"))
+		return v == 2
+	})
+	if calls.Load() != 2 {
+		t.Fatalf("fn called %d times, want 2", calls.Load())
+	}
+	if s := callonce.FromContext(ctx).Stats(); s.StaleHits < 10 {
+		t.Fatalf("stale hits = %d, want at least 10", s.StaleHits)
+	}
+}
+
+func TestStaleWhileRevalidateRefreshError(t *testing.T) {
+	clock := newFakeClock()
+	rec := &recordingObserver{}
+	ctx := callonce.WithCache(context.Background(),
+		callonce.WithTTL(time.Minute),
+		callonce.WithStaleWhileRevalidate(0),
+		callonce.WithClock(clock),
+		callonce.WithObserver(rec),
+	)
+
+	callonce.Get(ctx, func() (string, error) { return "old", nil }, callonce.L(testKey, "1"))
+	clock.Advance(time.Hour)
+
+	errRefresh := errors.New("refresh failed")
+	v, err := callonce.Get(ctx, func() (string, error) { return "", errRefresh }, callonce.L(testKey, "1"))
+	if v != "old" || err != nil {
+		t.Fatalf("got %q, %v; want stale %q, nil", v, err, "old")
+	}
+
+	var failed callonce.EventData
+	eventually(t, func() bool {
+		for _, e := range rec.snapshot() {
+			if e.Event == callonce.EventRefreshError {
+				failed = e
+				return true
+			}
+		}
+		return false
+	})
+	if !errors.Is(failed.Err, errRefresh) || failed.Identifier != "1" {
+		t.Fatalf("got %+v, want refresh error for identifier 1", failed)
+	}
+
+	// The stale value stays in place after a failed refresh.
+	v, _ = callonce.Get(ctx, func() (string, error) { return "", errRefresh }, callonce.L(testKey, "1"))
+	if v != "old" {
+		t.Fatalf("got %q after failed refresh, want %q", v, "old")
+	}
+}
+
+func TestStaleWhileRevalidateMaxStale(t *testing.T) {
+	clock := newFakeClock()
+	ctx := callonce.WithCache(context.Background(),
+		callonce.WithTTL(time.Minute),
+		callonce.WithStaleWhileRevalidate(time.Minute),
+		callonce.WithClock(clock),
+	)
+
+	callonce.Get(ctx, func() (string, error) { return "old", nil }, callonce.L(testKey, "1"))
+	clock.Advance(3 * time.Minute)
+
+	v, _ := callonce.Get(ctx, func() (string, error) { return "new", nil }, callonce.L(testKey, "1"))
+	if v != "new" {
+		t.Fatalf("got %q, want %q once past maxStale", v, "new")
+	}
+}
+
+func TestGetFromStaleWhileRevalidate(t *testing.T) {
+	clock := newFakeClock()
+	ctx := callonce.WithCache(context.Background(),
+		callonce.WithTTL(time.Minute),
+		callonce.WithStaleWhileRevalidate(0),
+		callonce.WithClock(clock),
+	)
+	c := callonce.FromContext(ctx)
+
+	callonce.GetFrom(ctx, c, func() (string, error) { return "old", nil }, callonce.L(testKey, "1"))
+	clock.Advance(time.Hour)
+
+	v, _ := callonce.GetFrom(ctx, c, func() (string, error) { return "new", nil }, callonce.L(testKey, "1"))
+	if v != "old" {
+		t.Fatalf("got %q, want the stale value %q", v, "old")
+	}
+	eventually(t, func() bool {
+		v, _ := callonce.GetFrom(ctx, c, func() (string, error) { return "miss", nil }, callonce.L(testKey, "1"))
+		return v == "new"
+	})
+}
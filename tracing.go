@@ -0,0 +1,97 @@
+package callonce
+
+import "context"
+
+// Tracer lets Get report fn executions to a tracing system without
+// callonce depending on one. The OpenTelemetry implementation lives in the
+// github.com/probablyarth/callonce-go/otel module.
+//
+// Implementations must be safe for concurrent use.
+type Tracer interface {
+	// Start is called on a miss, right before fn runs, with the context of
+	// the Get call that runs it. The returned Span is ended when fn
+	// returns.
+	Start(ctx context.Context, key, identifier string) Span
+
+	// Link is called for cache hits and dedup waiters with the context of
+	// the Get call and the Span of the call that produced the value. It
+	// typically adds a link or event to the span in ctx. leader is nil
+	// when the producing call was not traced.
+	Link(ctx context.Context, event Event, key, identifier string, leader Span)
+}
+
+// Span is a traced fn execution started by a Tracer.
+type Span interface {
+	// End finishes the span. err is the error returned by fn.
+	End(err error)
+}
+
+// WithTracer attaches a Tracer that starts a span around every fn call and
+// links hits and dedup waiters to the span that produced their value.
+func WithTracer(t Tracer) Option {
+	return func(cache *Cache) {
+		cache.tracer = t
+		cache.spans = make(map[StoreKey]Span)
+	}
+}
+
+// startSpan starts a span for the leader of a miss and records it so that
+// dedup waiters can link to it. It returns nil if no tracer is attached.
+func (c *Cache) startSpan(ctx context.Context, key StoreKey) Span {
+	if c.tracer == nil {
+		return nil
+	}
+	span := c.tracer.Start(ctx, key.Key, key.Identifier)
+	c.setSpan(key, span)
+	return span
+}
+
+// link reports a hit or dedup to the tracer, if any.
+func (c *Cache) link(ctx context.Context, event Event, keyName, identifier string, leader Span) {
+	if c.tracer == nil {
+		return
+	}
+	c.tracer.Link(ctx, event, keyName, identifier, leader)
+}
+
+// span returns the span that produced the value stored under key.
+func (c *Cache) span(key StoreKey) Span {
+	if c.tracer == nil {
+		return nil
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.spans[key]
+}
+
+// setSpan records span as the producer of key.
+func (c *Cache) setSpan(key StoreKey, span Span) {
+	if c.tracer == nil || span == nil {
+		return
+	}
+	c.mu.Lock()
+	c.spans[key] = span
+	c.mu.Unlock()
+}
+
+// forgetSpan drops the span recorded for key.
+func (c *Cache) forgetSpan(key StoreKey) {
+	if c.tracer == nil {
+		return
+	}
+	c.mu.Lock()
+	delete(c.spans, key)
+	c.mu.Unlock()
+}
+
+// forgetSpans drops the spans recorded for lookups.
+func forgetSpans[T any](c *Cache, lookups []Lookup[T]) {
+	if c.tracer == nil {
+		return
+	}
+	c.mu.Lock()
+	for _, l := range lookups {
+		delete(c.spans, l.storeKey())
+	}
+	c.mu.Unlock()
+}
@@ -0,0 +1,121 @@
+package callonce_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	callonce "github.com/probablyarth/callonce-go"
+)
+
+type fakeSpan struct {
+	key   string
+	ended bool
+	err   error
+}
+
+func (s *fakeSpan) End(err error) {
+	s.ended = true
+	s.err = err
+}
+
+type fakeLink struct {
+	event  callonce.Event
+	leader callonce.Span
+}
+
+type fakeTracer struct {
+	mu    sync.Mutex
+	spans []*fakeSpan
+	links []fakeLink
+}
+
+func (t *fakeTracer) Start(_ context.Context, key, identifier string) callonce.Span {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	s := &fakeSpan{key: key + "/" + identifier}
+	t.spans = append(t.spans, s)
+	return s
+}
+
+func (t *fakeTracer) Link(_ context.Context, event callonce.Event, _, _ string, leader callonce.Span) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.links = append(t.links, fakeLink{event: event, leader: leader})
+}
+
+func TestTracerSpanPerMiss(t *testing.T) {
+	tr := &fakeTracer{}
+	ctx := callonce.WithCache(context.Background(), callonce.WithTracer(tr))
+	errBoom := errors.New("boom")
+
+	callonce.Get(ctx, func() (string, error) { return "v", nil }, callonce.L(testKey, "1"))
+	callonce.Get(ctx, func() (string, error) { return "", errBoom }, callonce.L(testKey, "2"))
+
+	if len(tr.spans) != 2 {
+		t.Fatalf("got %d spans, want 2", len(tr.spans))
+	}
+	for _, s := range tr.spans {
+		if !s.ended {
+			t.Fatalf("span %s not ended", s.key)
+		}
+	}
+	if tr.spans[0].key != "string:test/1" {
+		t.Fatalf("span key = %q, want %q", tr.spans[0].key, "string:test/1")
+	}
+	if !errors.Is(tr.spans[1].err, errBoom) {
+		t.Fatalf("span err = %v, want %v", tr.spans[1].err, errBoom)
+	}
+}
+
+func TestTracerLinksHitsAndDedupsToLeader(t *testing.T) {
+	tr := &fakeTracer{}
+	ctx := callonce.WithCache(context.Background(), callonce.WithTracer(tr))
+
+	const n = 10
+	release := make(chan struct{})
+	started := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(n)
+	for i := 0; i < n; i++ {
+		go func() {
+			defer wg.Done()
+			callonce.Get(ctx, func() (string, error) {
+				close(started)
+				<-release
+				return "v", nil
+			}, callonce.L(testKey, "1"))
+		}()
+	}
+	<-started
+	time.Sleep(10 * time.Millisecond) // let the other goroutines join the flight
+	close(release)
+	wg.Wait()
+
+	callonce.Get(ctx, func() (string, error) { return "v", nil }, callonce.L(testKey, "1"))
+
+	if len(tr.spans) != 1 {
+		t.Fatalf("got %d spans, want 1", len(tr.spans))
+	}
+	leader := tr.spans[0]
+	var hits, dedups int
+	for _, l := range tr.links {
+		if l.leader != leader {
+			t.Fatalf("%v linked to %v, want the leader span", l.event, l.leader)
+		}
+		switch l.event {
+		case callonce.EventHit:
+			hits++
+		case callonce.EventDedup:
+			dedups++
+		}
+	}
+	if hits+dedups != n {
+		t.Fatalf("got %d hits + %d dedups, want %d", hits, dedups, n)
+	}
+	if s := callonce.FromContext(ctx).Stats(); s.Dedups != uint64(dedups) {
+		t.Fatalf("stats dedups = %d, want %d", s.Dedups, dedups)
+	}
+}
@@ -0,0 +1,82 @@
+package callonce
+
+import "time"
+
+// Clock tells the time. WithClock replaces the system clock used for
+// expiry, typically with a fake one in tests.
+type Clock interface {
+	Now() time.Time
+}
+
+// WithClock makes the cache read the time from clock when checking
+// expiry. The default is the system clock.
+func WithClock(clock Clock) Option {
+	return func(cache *Cache) {
+		cache.clock = clock
+	}
+}
+
+// WithTTL makes values expire d after they were stored. Get treats an
+// expired value as a miss and calls fn again. Values below or equal to 0
+// mean no expiry, which is the default.
+//
+// Expired values are not removed in the background; they stay in the store
+// until they are replaced, forgotten or evicted.
+func WithTTL(d time.Duration) Option {
+	return func(cache *Cache) {
+		cache.ttl = d
+	}
+}
+
+// WithKeyTTL sets the TTL for values of key, overriding WithTTL. A d below
+// or equal to 0 means values of key never expire.
+func WithKeyTTL[T any](key Key[T], d time.Duration) Option {
+	return func(cache *Cache) {
+		if cache.keyTTL == nil {
+			cache.keyTTL = make(map[string]time.Duration)
+		}
+		cache.keyTTL[key.name] = d
+	}
+}
+
+// expiring is a stored value with an expiry time.
+type expiring struct {
+	value   any
+	expires time.Time
+}
+
+func (c *Cache) now() time.Time {
+	if c.clock == nil {
+		return time.Now()
+	}
+	return c.clock.Now()
+}
+
+// expire wraps value with its expiry time when keyName has a TTL.
+func (c *Cache) expire(keyName string, value any) any {
+	ttl := c.ttl
+	if d, ok := c.keyTTL[keyName]; ok {
+		ttl = d
+	}
+	if ttl <= 0 {
+		return value
+	}
+	return &expiring{value: value, expires: c.now().Add(ttl)}
+}
+
+// unexpired unwraps a stored value, reporting false if it has expired.
+func (c *Cache) unexpired(stored any) (any, bool) {
+	e, ok := stored.(*expiring)
+	if !ok {
+		return stored, true
+	}
+	return e.value, c.now().Before(e.expires)
+}
+
+// storedValue returns the value a stored value holds, ignoring expiry.
+func storedValue(stored any) any {
+	if e, ok := stored.(*expiring); ok {
+		return e.value
+	}
+	return stored
+}
@@ -0,0 +1,113 @@
+package callonce_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	callonce "github.com/probablyarth/callonce-go"
+)
+
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	c.now = c.now.Add(d)
+	c.mu.Unlock()
+}
+
+func TestTTLRefetchesExpiredValues(t *testing.T) {
+	clock := newFakeClock()
+	ctx := callonce.WithCache(context.Background(),
+		callonce.WithTTL(time.Minute),
+		callonce.WithClock(clock),
+	)
+
+	calls := 0
+	fn := func() (int, error) {
+		calls++
+		return calls, nil
+	}
+	key := callonce.NewKey[int]("counter")
+
+	callonce.Get(ctx, fn, callonce.L(key, "1"))
+	clock.Advance(59 * time.Second)
+	if v, _ := callonce.Get(ctx, fn, callonce.L(key, "1")); v != 1 {
+		t.Fatalf("got %d before expiry, want 1", v)
+	}
+
+	clock.Advance(time.Second)
+	if v, _ := callonce.Get(ctx, fn, callonce.L(key, "1")); v != 2 {
+		t.Fatalf("got %d after expiry, want 2", v)
+	}
+
+	s := callonce.FromContext(ctx).Stats()
+	if s.Hits != 1 || s.Misses != 2 {
+		t.Fatalf("got hits=%d misses=%d; want 1, 2", s.Hits, s.Misses)
+	}
+}
+
+func TestKeyTTLOverridesCacheTTL(t *testing.T) {
+	clock := newFakeClock()
+	short := callonce.NewKey[string]("short")
+	forever := callonce.NewKey[string]("forever")
+	ctx := callonce.WithCache(context.Background(),
+		callonce.WithTTL(time.Minute),
+		callonce.WithKeyTTL(short, time.Second),
+		callonce.WithKeyTTL(forever, 0),
+		callonce.WithClock(clock),
+	)
+
+	calls := map[string]int{}
+	get := func(key callonce.Key[string], id string) {
+		callonce.Get(ctx, func() (string, error) {
+			calls[id]++
+			return id, nil
+		}, callonce.L(key, id))
+	}
+
+	get(short, "s")
+	get(forever, "f")
+	get(testKey, "d")
+
+	clock.Advance(2 * time.Second)
+	get(short, "s")
+	get(forever, "f")
+	get(testKey, "d")
+
+	clock.Advance(time.Hour)
+	get(forever, "f")
+	get(testKey, "d")
+
+	if calls["s"] != 2 || calls["f"] != 1 || calls["d"] != 2 {
+		t.Fatalf("got calls %v, want s=2 f=1 d=2", calls)
+	}
+}
+
+func TestTTLWithMaxBytesSizesUnwrappedValues(t *testing.T) {
+	ctx := callonce.WithCache(context.Background(),
+		callonce.WithTTL(time.Minute),
+		callonce.WithMaxBytes(8, func(v any) int { return len(v.(string)) }),
+	)
+
+	callonce.Get(ctx, func() (string, error) { return "1234", nil }, callonce.L(testKey, "a"))
+	callonce.Get(ctx, func() (string, error) { return "1234", nil }, callonce.L(testKey, "b"))
+
+	if s := callonce.FromContext(ctx).Stats(); s.Entries != 2 || s.Evictions != 0 {
+		t.Fatalf("got entries=%d evictions=%d; want 2, 0", s.Entries, s.Evictions)
+	}
+}